@@ -0,0 +1,238 @@
+// Command configcrypt encrypts and decrypts configuration values in the
+// ENC[AES256_GCM,...] format understood by the configuration package.
+//
+// Usage:
+//
+//	configcrypt keygen
+//	configcrypt encrypt [-key-file path] [value]
+//	configcrypt decrypt [-key-file path] [value]
+//	configcrypt rekey [-key-file path] -new-key-file path file...
+//
+// If -key-file is omitted the key is taken from CONFIG_ENCRYPTION_KEY or
+// CONFIG_ENCRYPTION_KEY_FILE. If value is omitted it is read from stdin.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ms-xy/go-common/configuration"
+)
+
+var encryptedValue = regexp.MustCompile(`ENC\[AES256_GCM,[A-Za-z0-9+/=]+\]`)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen()
+	case "encrypt":
+		err = encrypt(os.Args[2:])
+	case "decrypt":
+		err = decrypt(os.Args[2:])
+	case "rekey":
+		err = rekey(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "configcrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage:
+  configcrypt keygen
+  configcrypt encrypt [-key-file path] [value]
+  configcrypt decrypt [-key-file path] [value]
+  configcrypt rekey [-key-file path] -new-key-file path file...
+`)
+}
+
+func keygen() error {
+	key, err := configuration.GenerateEncryptionKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(configuration.EncodeEncryptionKey(key))
+	return nil
+}
+
+func loadKey(keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		return configuration.ReadEncryptionKeyFile(keyFile)
+	}
+	return configuration.LoadEncryptionKey()
+}
+
+// valueArg returns the single positional argument or, if none is given, the
+// contents of stdin without the trailing newline
+func valueArg(fs *flag.FlagSet) (string, error) {
+	switch fs.NArg() {
+	case 0:
+		buf, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(buf), "\r\n"), nil
+	case 1:
+		return fs.Arg(0), nil
+	default:
+		return "", fmt.Errorf("expected at most one value, got %d", fs.NArg())
+	}
+}
+
+func encrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "file containing the base64 encoded key")
+	fs.Parse(args)
+
+	key, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	value, err := valueArg(fs)
+	if err != nil {
+		return err
+	}
+	encrypted, err := configuration.EncryptValue(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	return nil
+}
+
+func decrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "file containing the base64 encoded key")
+	fs.Parse(args)
+
+	key, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	value, err := valueArg(fs)
+	if err != nil {
+		return err
+	}
+	plaintext, err := configuration.DecryptValue(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(plaintext)
+	return nil
+}
+
+// rekey re-encrypts every ENC[...] value within the given files using the new
+// key. All other content of the files is left untouched, so this works for
+// JSON and YAML alike.
+func rekey(args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "file containing the current base64 encoded key")
+	newKeyFile := fs.String("new-key-file", "", "file containing the new base64 encoded key")
+	fs.Parse(args)
+
+	if *newKeyFile == "" {
+		return fmt.Errorf("rekey requires -new-key-file")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("rekey requires at least one file")
+	}
+	oldKey, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	newKey, err := configuration.ReadEncryptionKeyFile(*newKeyFile)
+	if err != nil {
+		return err
+	}
+
+	for _, filepath := range fs.Args() {
+		if n, err := rekeyFile(filepath, oldKey, newKey); err != nil {
+			return fmt.Errorf("%s: %w", filepath, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: re-encrypted %d values\n", filepath, n)
+		}
+	}
+	return nil
+}
+
+func rekeyFile(filepath string, oldKey, newKey []byte) (int, error) {
+	info, err := os.Stat(filepath)
+	if err != nil {
+		return 0, err
+	}
+	buf, err := os.ReadFile(filepath)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		n        int
+		rekeyErr error
+	)
+	out := encryptedValue.ReplaceAllFunc(buf, func(match []byte) []byte {
+		if rekeyErr != nil {
+			return match
+		}
+		plaintext, err := configuration.DecryptValue(oldKey, string(match))
+		if err != nil {
+			rekeyErr = err
+			return match
+		}
+		encrypted, err := configuration.EncryptValue(newKey, plaintext)
+		if err != nil {
+			rekeyErr = err
+			return match
+		}
+		n++
+		return []byte(encrypted)
+	})
+	if rekeyErr != nil {
+		return 0, rekeyErr
+	}
+	return n, writeFileAtomic(filepath, out, info.Mode())
+}
+
+// writeFileAtomic replaces the file name with data by writing a temporary file
+// in the same directory and renaming it, so a crash never leaves a truncated
+// file behind
+func writeFileAtomic(name string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,171 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ms-xy/go-common/log"
+)
+
+// RedactedValue replaces the values of secret keys in a ConfigDiff
+const RedactedValue = log.RedactedValue
+
+// SecretKeyPatterns lists the (lower case) substrings which mark a key as
+// secret if contained in any of its segments. It starts out as a copy of
+// log.DefaultSecretKeyPatterns, changing one does not affect the other.
+var SecretKeyPatterns = append([]string(nil), log.DefaultSecretKeyPatterns...)
+
+// KeyChange describes the difference of a single key. Old is nil for added
+// keys, New is nil for removed keys.
+type KeyChange struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// ConfigDiff is the result of Diff, all lists are sorted by key
+type ConfigDiff struct {
+	Added   []KeyChange
+	Removed []KeyChange
+	Changed []KeyChange
+}
+
+// Diff compares the configuration a (e.g. currently running) with b (e.g. the
+// candidate) and returns the added, removed and changed keys.
+// Values of secret keys (see SecretKeyPatterns) and of keys which were
+// encrypted in either configuration (see EncryptedKeyReporter) are redacted.
+// Numbers are compared by value, regardless of their type.
+//
+// Both loaders must implement KeyLister.
+func Diff(a, b ConfigurationLoader) (*ConfigDiff, error) {
+	aKeys, aOk := a.(KeyLister)
+	bKeys, bOk := b.(KeyLister)
+	if !aOk || !bOk {
+		return nil, errors.New("both configurations must implement KeyLister to be compared")
+	}
+
+	diff := &ConfigDiff{
+		Added:   make([]KeyChange, 0),
+		Removed: make([]KeyChange, 0),
+		Changed: make([]KeyChange, 0),
+	}
+	redact := func(change KeyChange) KeyChange {
+		if isSecretKey(change.Key) || isEncryptedKey(a, change.Key) || isEncryptedKey(b, change.Key) {
+			if change.Old != nil {
+				change.Old = RedactedValue
+			}
+			if change.New != nil {
+				change.New = RedactedValue
+			}
+		}
+		return change
+	}
+	inB := make(map[string]struct{})
+	for _, key := range bKeys.Keys() {
+		inB[key] = struct{}{}
+	}
+	for _, key := range aKeys.Keys() {
+		if _, exists := inB[key]; !exists {
+			diff.Removed = append(diff.Removed, redact(KeyChange{Key: key, Old: a.Get(key)}))
+			continue
+		}
+		delete(inB, key)
+		oldValue, newValue := a.Get(key), b.Get(key)
+		if !valuesEqual(oldValue, newValue) {
+			diff.Changed = append(diff.Changed, redact(KeyChange{Key: key, Old: oldValue, New: newValue}))
+		}
+	}
+	for key := range inB {
+		diff.Added = append(diff.Added, redact(KeyChange{Key: key, New: b.Get(key)}))
+	}
+
+	for _, changes := range [][]KeyChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	}
+	return diff, nil
+}
+
+// valuesEqual compares a and b deeply after converting all numbers to
+// float64, as e.g. JSON yields float64 where YAML yields int for the same
+// number
+func valuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeNumbers(a), normalizeNumbers(b))
+}
+
+// normalizeNumbers returns v with all numbers within converted to float64
+func normalizeNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			m[k] = normalizeNumbers(child)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, child := range value {
+			s[i] = normalizeNumbers(child)
+		}
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, segment := range strings.Split(key, ".") {
+		for _, pattern := range SecretKeyPatterns {
+			if strings.Contains(segment, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isEncryptedKey(loader ConfigurationLoader, key string) bool {
+	r, ok := loader.(EncryptedKeyReporter)
+	return ok && r.IsEncryptedKey(key)
+}
+
+// Empty reports whether there are no differences
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// WriteText renders the diff line by line, prefixing added keys with '+',
+// removed keys with '-' and changed keys with '~'
+func (d *ConfigDiff) WriteText(w io.Writer) error {
+	_, err := io.WriteString(w, d.String())
+	return err
+}
+
+func (d *ConfigDiff) String() string {
+	if d.Empty() {
+		return "no configuration changes\n"
+	}
+	buf := new(strings.Builder)
+	for _, c := range d.Added {
+		fmt.Fprintf(buf, "+ %s = %v\n", c.Key, c.New)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(buf, "- %s = %v\n", c.Key, c.Old)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(buf, "~ %s: %v -> %v\n", c.Key, c.Old, c.New)
+	}
+	return buf.String()
+}
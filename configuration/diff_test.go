@@ -0,0 +1,113 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ms-xy/go-common/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	running := NewMapConfigLoader(map[string]interface{}{
+		"anInt": 3600.0,
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"password": "old",
+			"user":     "admin",
+		},
+		"removed": true,
+	}, "", "test", ".")
+	candidate := NewMapConfigLoader(map[string]interface{}{
+		"anInt": 3600,
+		"db": map[string]interface{}{
+			"host":     "db.internal",
+			"password": "new",
+			"user":     "admin",
+		},
+		"added": "value",
+	}, "", "test", ".")
+
+	diff, err := Diff(running, candidate)
+	require.Nil(t, err)
+	require.Equal(t, []KeyChange{{Key: "added", New: "value"}}, diff.Added)
+	require.Equal(t, []KeyChange{{Key: "removed", Old: true}}, diff.Removed)
+	require.Equal(t, []KeyChange{
+		{Key: "db.host", Old: "localhost", New: "db.internal"},
+		{Key: "db.password", Old: RedactedValue, New: RedactedValue},
+	}, diff.Changed)
+	require.Equal(t, "+ added = value\n"+
+		"- removed = true\n"+
+		"~ db.host: localhost -> db.internal\n"+
+		"~ db.password: <redacted> -> <redacted>\n", diff.String())
+
+	diff, err = Diff(running, running.Snapshot())
+	require.Nil(t, err)
+	require.True(t, diff.Empty())
+}
+
+func TestDiffTypesAndSecrets(t *testing.T) {
+	running := NewMapConfigLoader(map[string]interface{}{
+		"port":    "8080",
+		"enabled": "true",
+		"ratio":   1,
+		"limits":  []interface{}{1, 2},
+		"secrets": map[string]interface{}{"db": "old"},
+		"plugins": map[string]interface{}{},
+	}, "", "test", ".")
+	candidate := NewMapConfigLoader(map[string]interface{}{
+		"port":    8080,
+		"enabled": true,
+		"ratio":   1.0,
+		"limits":  []interface{}{1.0, 2.0},
+		"secrets": map[string]interface{}{"db": "new"},
+		"tags":    map[string]interface{}{},
+	}, "", "test", ".")
+
+	diff, err := Diff(running, candidate)
+	require.Nil(t, err)
+	require.Equal(t, []KeyChange{{Key: "tags", New: map[string]interface{}{}}}, diff.Added)
+	require.Equal(t, []KeyChange{{Key: "plugins", Old: map[string]interface{}{}}}, diff.Removed)
+	require.Equal(t, []KeyChange{
+		{Key: "enabled", Old: "true", New: true},
+		{Key: "port", Old: "8080", New: 8080},
+		{Key: "secrets.db", Old: RedactedValue, New: RedactedValue},
+	}, diff.Changed)
+
+	// the patterns are not shared with the log package
+	SecretKeyPatterns[0] = "changed"
+	defer func() { SecretKeyPatterns[0] = "password" }()
+	require.Equal(t, "password", log.DefaultSecretKeyPatterns[0])
+}
+
+func TestDiffRedactsEncryptedKeys(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	require.Nil(t, err)
+	t.Setenv(EncryptionKeyEnv, EncodeEncryptionKey(key))
+	t.Setenv(EncryptionKeyFileEnv, "")
+
+	load := func(dsn string) *MapConfigLoader {
+		encrypted, err := EncryptValue(key, dsn)
+		require.Nil(t, err)
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		content := "db:\n  dsn: " + encrypted + "\n  hosts:\n    - " + encrypted + "\n"
+		require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+		loader, err := LoadYamlConfiguration(path)
+		require.Nil(t, err)
+		return loader
+	}
+	running, candidate := load("postgres://old"), load("postgres://new")
+	require.True(t, candidate.IsEncryptedKey("db.dsn"))
+	require.True(t, candidate.IsEncryptedKey("db"))
+	require.False(t, candidate.IsEncryptedKey("d"))
+
+	combined := NewCombinedLoader()
+	combined.addLoader(candidate)
+	diff, err := Diff(running, combined.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, []KeyChange{
+		{Key: "db.dsn", Old: RedactedValue, New: RedactedValue},
+		{Key: "db.hosts", Old: RedactedValue, New: RedactedValue},
+	}, diff.Changed)
+}
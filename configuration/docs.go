@@ -0,0 +1,173 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// KeyDoc describes a single configuration key as declared on a settings
+// struct.
+//
+// The following struct tags are evaluated:
+//
+//	config:"db.host,required"  key (relative to the parent struct) and flags
+//	default:"localhost"        default value
+//	env:"DB_HOST"              environment variable, defaults to the full key
+//	desc:"database host"       description
+//
+// Fields without a config tag are skipped, unless they are structs, in which
+// case their fields are documented without an additional key prefix.
+// A config tag of "-" skips the field entirely.
+//
+// CombinedLoader.LoadSettings applies the env, default and required tags.
+type KeyDoc struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Env         string `json:"env"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// DescribeSettings returns the documentation of all keys declared on
+// settings, which must be a struct or a pointer to one
+func DescribeSettings(settings interface{}) ([]KeyDoc, error) {
+	t := reflect.TypeOf(settings)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("settings must be a struct or a pointer to a struct")
+	}
+	docs := make([]KeyDoc, 0, t.NumField())
+	return describeStruct(t, "", docs), nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var timeType = reflect.TypeOf(time.Time{})
+
+func describeStruct(t reflect.Type, prefix string, docs []KeyDoc) []KeyDoc {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("config")
+		if tag == "-" {
+			continue
+		}
+		name, flags, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		isNested := fieldType.Kind() == reflect.Struct && fieldType != timeType
+
+		if !hasTag || name == "" {
+			if isNested {
+				docs = describeStruct(fieldType, prefix, docs)
+			}
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if isNested {
+			docs = describeStruct(fieldType, key, docs)
+			continue
+		}
+
+		env, hasEnv := field.Tag.Lookup("env")
+		if !hasEnv {
+			env = key
+		}
+		docs = append(docs, KeyDoc{
+			Key:         key,
+			Type:        typeName(field.Type),
+			Default:     field.Tag.Get("default"),
+			Env:         env,
+			Description: field.Tag.Get("desc"),
+			Required:    hasFlag(flags, "required"),
+		})
+	}
+	return docs
+}
+
+func hasFlag(flags, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if strings.TrimSpace(f) == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "duration"
+	case t.Kind() == reflect.Ptr:
+		return typeName(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return "list of " + typeName(t.Elem())
+	case t.Kind() == reflect.Map:
+		return "map of " + typeName(t.Key()) + " to " + typeName(t.Elem())
+	}
+	return t.String()
+}
+
+// WriteMarkdownDocs writes a Markdown table documenting all keys of settings
+func WriteMarkdownDocs(w io.Writer, settings interface{}) error {
+	docs, err := DescribeSettings(settings)
+	if err != nil {
+		return err
+	}
+	lines := []string{
+		"| Key | Type | Default | Env | Required | Description |",
+		"| --- | --- | --- | --- | --- | --- |",
+	}
+	for _, d := range docs {
+		required := "no"
+		if d.Required {
+			required = "yes"
+		}
+		lines = append(lines, fmt.Sprintf("| `%s` | %s | %s | `%s` | %s | %s |",
+			d.Key, markdownEscape(d.Type), markdownCode(d.Default), d.Env, required,
+			markdownEscape(d.Description)))
+	}
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// WriteJSONDocs writes the documentation of all keys of settings as a JSON
+// array of KeyDoc objects
+func WriteJSONDocs(w io.Writer, settings interface{}) error {
+	docs, err := DescribeSettings(settings)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(docs)
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownEscape(s) + "`"
+}
@@ -0,0 +1,55 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testDbSettings struct {
+	Host    string        `config:"host,required" env:"DB_HOST" desc:"database host"`
+	Port    int           `config:"port" default:"5432" desc:"database port"`
+	Timeout time.Duration `config:"timeout" default:"5s"`
+}
+
+type testSettings struct {
+	Name     string         `config:"name" default:"app" desc:"name | alias"`
+	Db       testDbSettings `config:"db"`
+	Tags     []string       `config:"tags"`
+	Ignored  string         `config:"-"`
+	Untagged string
+}
+
+func TestDescribeSettings(t *testing.T) {
+	docs, err := DescribeSettings(&testSettings{})
+	require.Nil(t, err)
+	require.Equal(t, []KeyDoc{
+		{Key: "name", Type: "string", Default: "app", Env: "name", Description: "name | alias"},
+		{Key: "db.host", Type: "string", Env: "DB_HOST", Description: "database host", Required: true},
+		{Key: "db.port", Type: "int", Default: "5432", Env: "db.port", Description: "database port"},
+		{Key: "db.timeout", Type: "duration", Default: "5s", Env: "db.timeout"},
+		{Key: "tags", Type: "list of string", Env: "tags"},
+	}, docs)
+
+	_, err = DescribeSettings(42)
+	require.NotNil(t, err)
+}
+
+func TestWriteDocs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.Nil(t, WriteMarkdownDocs(buf, testSettings{}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 7)
+	require.Equal(t, "| `name` | string | `app` | `name` | no | name \\| alias |", lines[2])
+	require.Equal(t, "| `db.host` | string |  | `DB_HOST` | yes | database host |", lines[3])
+
+	buf.Reset()
+	require.Nil(t, WriteJSONDocs(buf, testSettings{}))
+	var docs []KeyDoc
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &docs))
+	require.Len(t, docs, 5)
+}
@@ -0,0 +1,205 @@
+package configuration
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// EncryptionKeyEnv is the environment variable holding the base64 encoded
+	// key used to decrypt ENC[...] configuration values
+	EncryptionKeyEnv = "CONFIG_ENCRYPTION_KEY"
+	// EncryptionKeyFileEnv is the environment variable holding the path of a
+	// file which contains the base64 encoded key, it is only consulted if
+	// EncryptionKeyEnv is not set
+	EncryptionKeyFileEnv = "CONFIG_ENCRYPTION_KEY_FILE"
+
+	// EncryptionKeySize is the required key length in bytes (AES-256)
+	EncryptionKeySize = 32
+
+	encryptedPrefix = "ENC[AES256_GCM,"
+	encryptedSuffix = "]"
+)
+
+var ErrNoEncryptionKey = errors.New("configuration contains encrypted values but no key is set in " +
+	EncryptionKeyEnv + " or " + EncryptionKeyFileEnv)
+
+// IsEncryptedValue reports whether value has the form ENC[AES256_GCM,...]
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// GenerateEncryptionKey returns a new random key suitable for EncryptValue
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncodeEncryptionKey returns the textual representation of key as expected
+// in EncryptionKeyEnv or the key file
+func EncodeEncryptionKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodeEncryptionKey parses the textual representation of a key
+func DecodeEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, NewError("malformed encryption key", err)
+	}
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// ReadEncryptionKeyFile reads a base64 encoded key from filepath
+func ReadEncryptionKeyFile(filepath string) ([]byte, error) {
+	buf, err := readFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeEncryptionKey(string(buf))
+}
+
+// LoadEncryptionKey resolves the key from EncryptionKeyEnv or, if that is not
+// set, from the file named by EncryptionKeyFileEnv.
+// Returns ErrNoEncryptionKey if neither is set.
+func LoadEncryptionKey() ([]byte, error) {
+	if encoded := os.Getenv(EncryptionKeyEnv); encoded != "" {
+		return DecodeEncryptionKey(encoded)
+	}
+	if filepath := os.Getenv(EncryptionKeyFileEnv); filepath != "" {
+		return ReadEncryptionKeyFile(filepath)
+	}
+	return nil, ErrNoEncryptionKey
+}
+
+// EncryptValue encrypts plaintext with AES-256-GCM and returns it in the
+// ENC[AES256_GCM,<base64 nonce+ciphertext>] form understood by the loaders
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// DecryptValue reverses EncryptValue
+func DecryptValue(key []byte, value string) (string, error) {
+	if !IsEncryptedValue(value) {
+		return "", errors.New("value is not of the form " + encryptedPrefix + "..." + encryptedSuffix)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)])
+	if err != nil {
+		return "", NewError("malformed encrypted value", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value: too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", NewError("unable to decrypt value (wrong key?)", err)
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptValues replaces all encrypted string values within data in place and
+// returns the paths of the replaced values. Values within lists are reported
+// by the path of the list.
+// The key is only resolved once the first encrypted value is encountered, so
+// configurations without encrypted values never require a key.
+func decryptValues(data map[string]interface{}) ([][]string, error) {
+	var key []byte
+	decrypted := make([][]string, 0)
+	err := decryptTraverse(data, &key, []string{}, nil, &decrypted)
+	return decrypted, err
+}
+
+// decryptTraverse decrypts the values within v. trail is the path of v used
+// in error messages, owner the path of the outermost list containing v, if any.
+func decryptTraverse(v interface{}, key *[]byte, trail, owner []string, decrypted *[][]string) error {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			_trail := append(trail[:len(trail):len(trail)], k)
+			_owner := owner
+			if _owner == nil {
+				_owner = _trail
+			}
+			if str, ok := child.(string); ok && IsEncryptedValue(str) {
+				plaintext, err := decryptWithKey(key, str, _trail)
+				if err != nil {
+					return err
+				}
+				value[k] = plaintext
+				*decrypted = append(*decrypted, _owner)
+			} else if err := decryptTraverse(child, key, _trail, owner, decrypted); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if owner == nil {
+			owner = trail
+		}
+		for i, child := range value {
+			_trail := append(trail[:len(trail):len(trail)], fmt.Sprint(i))
+			if str, ok := child.(string); ok && IsEncryptedValue(str) {
+				plaintext, err := decryptWithKey(key, str, _trail)
+				if err != nil {
+					return err
+				}
+				value[i] = plaintext
+				*decrypted = append(*decrypted, owner)
+			} else if err := decryptTraverse(child, key, _trail, owner, decrypted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decryptWithKey(key *[]byte, value string, trail []string) (string, error) {
+	if *key == nil {
+		k, err := LoadEncryptionKey()
+		if err != nil {
+			return "", err
+		}
+		*key = k
+	}
+	plaintext, err := DecryptValue(*key, value)
+	if err != nil {
+		return "", NewError("unable to decrypt key "+strings.Join(trail, "."), err)
+	}
+	return plaintext, nil
+}
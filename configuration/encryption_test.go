@@ -0,0 +1,62 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecryptValue(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	require.Nil(t, err)
+
+	encrypted, err := EncryptValue(key, "s3cr3t")
+	require.Nil(t, err)
+	require.True(t, IsEncryptedValue(encrypted))
+
+	plaintext, err := DecryptValue(key, encrypted)
+	require.Nil(t, err)
+	require.Equal(t, "s3cr3t", plaintext)
+
+	otherKey, err := GenerateEncryptionKey()
+	require.Nil(t, err)
+	_, err = DecryptValue(otherKey, encrypted)
+	require.NotNil(t, err)
+}
+
+func TestLoadEncryptedConfiguration(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	require.Nil(t, err)
+	encrypted, err := EncryptValue(key, "hunter2")
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "db:\n  user: admin\n  password: " + encrypted + "\n"
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+
+	t.Setenv(EncryptionKeyEnv, "")
+	t.Setenv(EncryptionKeyFileEnv, "")
+	_, err = LoadYamlConfiguration(path)
+	require.Equal(t, ErrNoEncryptionKey, err)
+
+	keyPath := filepath.Join(dir, "key")
+	require.Nil(t, os.WriteFile(keyPath, []byte(EncodeEncryptionKey(key)+"\n"), 0600))
+	t.Setenv(EncryptionKeyFileEnv, keyPath)
+
+	loader, err := LoadYamlConfiguration(path)
+	require.Nil(t, err)
+	require.Equal(t, "hunter2", loader.Get("db.password"))
+	require.Equal(t, "admin", loader.Get("db.user"))
+
+	// the key itself is never part of the configuration
+	t.Setenv(EncryptionKeyEnv, EncodeEncryptionKey(key))
+	t.Setenv("DB_PASSWORD", encrypted)
+	env, err := LoadEnvConfiguration()
+	require.Nil(t, err)
+	require.Equal(t, "hunter2", env.Get("DB_PASSWORD"))
+	require.Nil(t, env.Get(EncryptionKeyEnv))
+	require.Nil(t, env.Get(EncryptionKeyFileEnv))
+}
@@ -7,7 +7,9 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/ms-xy/go-common/log"
 	"gopkg.in/yaml.v3"
@@ -32,51 +34,150 @@ type ConfigurationLoader interface {
 	DumpConfig()
 }
 
+// loaders can be used to configure log levels, see log.LoadLevelOverrides
+var _ log.ConfigGetter = ConfigurationLoader(nil)
+
 // -------------------------------------------------------------------------- //
 
+// CombinedLoader resolves keys by asking each of its loaders in the order
+// they were added.
+//
+// It is safe for concurrent use, layers may be loaded while other goroutines
+// read values.
 type CombinedLoader struct {
+	mu         sync.RWMutex
 	loaders    []ConfigurationLoader
 	loaderInfo string
+	opts       []LoaderOption
+	// defaultLayers is the number of loaders holding default values (see
+	// LoadSettings), they are kept behind all other loaders
+	defaultLayers int
 }
 
 var _ ConfigurationLoader = (*CombinedLoader)(nil)
 
 // NewCombinedLoader supplies the means of loading a variadic set of settings
 //
+// The given options are applied to every loader created by the Load*, Must*
+// and Can* methods.
+//
 // Example:
 //
 //		NewCombinedLoader().MustLoadJSON(filepath).LoadEnv().CanLoadYaml(filepath)
-func NewCombinedLoader() *CombinedLoader {
+func NewCombinedLoader(opts ...LoaderOption) *CombinedLoader {
 	cl := new(CombinedLoader)
 	cl.loaders = make([]ConfigurationLoader, 0)
 	cl.loaderInfo = ""
+	cl.opts = opts
 	return cl
 }
 
 func (cl *CombinedLoader) DumpConfig() {
-	for _, l := range cl.loaders {
+	for _, l := range cl.getLoaders() {
 		l.DumpConfig()
 	}
 }
 
-func (cl *CombinedLoader) updateLoaderInfo(loader ConfigurationLoader) {
+// getLoaders returns the current set of loaders. The returned slice is never
+// modified afterwards, addLoader always allocates a new one.
+func (cl *CombinedLoader) getLoaders() []ConfigurationLoader {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.loaders
+}
+
+func (cl *CombinedLoader) addLoader(loader ConfigurationLoader) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	i := len(cl.loaders) - cl.defaultLayers
+	loaders := make([]ConfigurationLoader, 0, len(cl.loaders)+1)
+	loaders = append(loaders, cl.loaders[:i]...)
+	loaders = append(loaders, loader)
+	cl.loaders = append(loaders, cl.loaders[i:]...)
 	if cl.loaderInfo != "" {
 		cl.loaderInfo += ", "
 	}
 	cl.loaderInfo = fmt.Sprintf("%s%s", cl.loaderInfo, loader)
 }
 
+func (cl *CombinedLoader) String() string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return "CombinedLoader[" + cl.loaderInfo + "]"
+}
+
+// Keys returns the union of the keys of all loaders implementing KeyLister,
+// sorted
+func (cl *CombinedLoader) Keys() []string {
+	keys := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, l := range cl.getLoaders() {
+		if kl, ok := l.(KeyLister); ok {
+			for _, key := range kl.Keys() {
+				if _, exists := seen[key]; !exists {
+					seen[key] = struct{}{}
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IsEncryptedKey reports whether any loader implementing EncryptedKeyReporter
+// decrypted the value of key
+func (cl *CombinedLoader) IsEncryptedKey(key string) bool {
+	for _, l := range cl.getLoaders() {
+		if r, ok := l.(EncryptedKeyReporter); ok && r.IsEncryptedKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reloads all loaders which support reloading (see
+// MapConfigLoader.Reload). Snapshots taken before are not affected.
+func (cl *CombinedLoader) Reload() error {
+	for _, l := range cl.getLoaders() {
+		if r, ok := l.(Reloader); ok {
+			if err := r.Reload(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Snapshot returns an immutable view of the current configuration. Loading
+// further layers or reloading does not affect the snapshot.
+func (cl *CombinedLoader) Snapshot() *Snapshot {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	frozen := &CombinedLoader{
+		loaders:    make([]ConfigurationLoader, len(cl.loaders)),
+		loaderInfo: cl.loaderInfo,
+	}
+	for i, l := range cl.loaders {
+		if s, ok := l.(Snapshotter); ok {
+			frozen.loaders[i] = s.Snapshot()
+		} else {
+			frozen.loaders[i] = l
+		}
+	}
+	return &Snapshot{loader: frozen}
+}
+
 // LoadEnv attempts to load env variables as configuration for a MapConfigLoader
 //
 // If the given variables create ambiguous config paths, an error will be
 // returned instead (e.g. my.path=10 my.path.subpath=12 would conflict for the
 // my.path part)
 func (cl *CombinedLoader) LoadEnv() error {
-	if mcl, err := LoadEnvConfiguration(); err != nil {
+	if mcl, err := LoadEnvConfiguration(cl.opts...); err != nil {
 		return err
 	} else {
-		cl.loaders = append(cl.loaders, mcl)
-		cl.updateLoaderInfo(mcl)
+		cl.addLoader(mcl)
 		return nil
 	}
 }
@@ -102,11 +203,10 @@ func (cl *CombinedLoader) CanLoadEnv() *CombinedLoader {
 // LoadJSON attempts to load the given filepath as a JSON file and create a
 // MapConfigLoader based on it
 func (cl *CombinedLoader) LoadJSON(filepath string) error {
-	if mcl, err := LoadJsonConfiguration(filepath); err != nil {
+	if mcl, err := LoadJsonConfiguration(filepath, cl.opts...); err != nil {
 		return err
 	} else {
-		cl.loaders = append(cl.loaders, mcl)
-		cl.updateLoaderInfo(mcl)
+		cl.addLoader(mcl)
 		return nil
 	}
 }
@@ -134,11 +234,10 @@ func (cl *CombinedLoader) CanLoadJSON(filepath string) *CombinedLoader {
 // LoadYaml attempts to load the given filepath as a YAML config file and
 // returns any errors encountered
 func (cl *CombinedLoader) LoadYaml(filepath string) error {
-	if mcl, err := LoadYamlConfiguration(filepath); err != nil {
+	if mcl, err := LoadYamlConfiguration(filepath, cl.opts...); err != nil {
 		return err
 	} else {
-		cl.loaders = append(cl.loaders, mcl)
-		cl.updateLoaderInfo(mcl)
+		cl.addLoader(mcl)
 		return nil
 	}
 }
@@ -161,9 +260,78 @@ func (cl *CombinedLoader) CanLoadYaml(filepath string) *CombinedLoader {
 	return cl
 }
 
+// LoadSettings applies the tags of settings (see KeyDoc):
+//
+//   - the environment variables named by the env tags (or the keys themselves)
+//     take precedence over all other loaders
+//   - the values of default tags are used if no loader has a value for a key,
+//     including loaders added later on
+//   - required keys without a value are reported as an error
+//
+// Required keys are checked against the loaders added so far, so call it after
+// loading all other layers. Like other environment variables, the values of
+// env and default tags are strings, which GetTypeSafe unmarshals as JSON.
+func (cl *CombinedLoader) LoadSettings(settings interface{}) error {
+	docs, err := DescribeSettings(settings)
+	if err != nil {
+		return err
+	}
+	envKeys := make(map[string]string, len(docs))
+	defaults := make(map[string]interface{})
+	for _, d := range docs {
+		envKeys[d.Env] = d.Key
+		if d.Default != "" {
+			if err := loadKvRecursive(defaults, strings.Split(d.Key, "."), d.Default, []string{}); err != nil {
+				return err
+			}
+		}
+	}
+	env, err := loadSettingsEnv(envKeys, cl.opts...)
+	if err != nil {
+		return err
+	}
+	defaultsLoader, err := LoadMapConfiguration(defaults, "default values", "defaults", ".", cl.opts...)
+	if err != nil {
+		return err
+	}
+
+	cl.mu.Lock()
+	loaders := make([]ConfigurationLoader, 0, len(cl.loaders)+2)
+	loaders = append(loaders, env)
+	loaders = append(loaders, cl.loaders...)
+	cl.loaders = append(loaders, defaultsLoader)
+	cl.defaultLayers++
+	info := []string{env.String()}
+	if cl.loaderInfo != "" {
+		info = append(info, cl.loaderInfo)
+	}
+	cl.loaderInfo = strings.Join(append(info, defaultsLoader.String()), ", ")
+	cl.mu.Unlock()
+
+	missing := make([]string, 0)
+	for _, d := range docs {
+		if d.Required && cl.Get(d.Key) == nil {
+			missing = append(missing, d.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("missing required configuration keys: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// MustLoadSettings uses LoadSettings, but panics if an error is returned,
+// otherwise it returns the loader again for call chaining
+func (cl *CombinedLoader) MustLoadSettings(settings interface{}) *CombinedLoader {
+	if err := cl.LoadSettings(settings); err != nil {
+		panic(err)
+	}
+	return cl
+}
+
 // Returns the value associated with key or nil
 func (cl *CombinedLoader) Get(key string) interface{} {
-	for _, loader := range cl.loaders {
+	for _, loader := range cl.getLoaders() {
 		if v := loader.Get(key); v != nil {
 			return v
 		}
@@ -184,7 +352,10 @@ func (cl *CombinedLoader) Must(key string) interface{} {
 	if v := cl.Get(key); v != nil {
 		return v
 	}
-	panic("Key " + key + " not found in " + cl.loaderInfo)
+	cl.mu.RLock()
+	loaderInfo := cl.loaderInfo
+	cl.mu.RUnlock()
+	panic("Key " + key + " not found in " + loaderInfo)
 }
 
 // Writes the value found using key if - and only if - it either matches the
@@ -193,7 +364,7 @@ func (cl *CombinedLoader) Must(key string) interface{} {
 // dest must be a pointer
 func (cl *CombinedLoader) GetTypeSafe(key string, ptrDest interface{}) error {
 	_err := make([]error, 0)
-	for _, loader := range cl.loaders {
+	for _, loader := range cl.getLoaders() {
 		if err := loader.GetTypeSafe(key, ptrDest); err == nil {
 			return nil
 		} else {
@@ -222,17 +393,30 @@ func (cl *CombinedLoader) GetTypeSafeOrDefault(key string, ptrDest interface{},
 // MapConfigLoader is a config loader which will interprete keys with dots
 // as key-chains within map structures.
 // E.g. loader.get(my.fancy.key) is resolved to data[my][fancy][key]
+//
+// String values of the form ENC[AES256_GCM,...] are decrypted when loading
+// from a file or the environment, see LoadEncryptionKey.
+//
+// It is safe for concurrent use. The data map is never modified after it was
+// handed to the loader, Reload swaps in a new map instead.
 type MapConfigLoader struct {
+	mu         sync.RWMutex
 	data       map[string]interface{}
 	filepath   string
 	sep        string
 	configType string
+	normalize  bool
+	opts       []LoaderOption
+	// encrypted holds the keys whose values were decrypted, joined by sep
+	encrypted map[string]struct{}
+	// envKeys maps environment variables to keys, see loadSettingsEnv
+	envKeys map[string]string
 }
 
 var _ ConfigurationLoader = (*MapConfigLoader)(nil)
 
 func (mcl *MapConfigLoader) DumpConfig() {
-	log.WithFields(mcl.data).Infof("configuration values obtained from '%s':", mcl.filepath)
+	log.WithFields(mcl.getData()).Infof("configuration values obtained from '%s':", mcl.filepath)
 }
 
 func readFile(filepath string) ([]byte, error) {
@@ -243,16 +427,45 @@ func readFile(filepath string) ([]byte, error) {
 	}
 }
 
-func NewMapConfigLoader(data map[string]interface{}, filepath, configType, keySeparator string) *MapConfigLoader {
-	return &MapConfigLoader{
-		data:       data,
+// NewMapConfigLoader creates a loader for data. data must not be modified by
+// the caller afterwards.
+//
+// Panics if the options can not be applied to data (e.g. on key collisions
+// with WithKeyNormalization), use LoadMapConfiguration to receive an error
+// instead.
+func NewMapConfigLoader(data map[string]interface{}, filepath, configType, keySeparator string, opts ...LoaderOption) *MapConfigLoader {
+	if mcl, err := LoadMapConfiguration(data, filepath, configType, keySeparator, opts...); err != nil {
+		panic(err)
+	} else {
+		return mcl
+	}
+}
+
+// LoadMapConfiguration creates a loader for data, returning an error if the
+// options can not be applied to data. data must not be modified by the caller
+// afterwards.
+func LoadMapConfiguration(data map[string]interface{}, filepath, configType, keySeparator string, opts ...LoaderOption) (*MapConfigLoader, error) {
+	mcl := &MapConfigLoader{
 		filepath:   filepath,
 		sep:        keySeparator,
 		configType: configType,
+		opts:       opts,
 	}
+	for _, opt := range opts {
+		opt(mcl)
+	}
+	if mcl.normalize {
+		normalized, err := normalizeKeys(data, []string{})
+		if err != nil {
+			return nil, NewError("unable to load "+mcl.String(), err)
+		}
+		data = normalized
+	}
+	mcl.data = data
+	return mcl, nil
 }
 
-func LoadJsonConfiguration(filepath string) (*MapConfigLoader, error) {
+func LoadJsonConfiguration(filepath string, opts ...LoaderOption) (*MapConfigLoader, error) {
 	if buf, err := readFile(filepath); err != nil {
 		return nil, err
 	} else {
@@ -260,11 +473,11 @@ func LoadJsonConfiguration(filepath string) (*MapConfigLoader, error) {
 		if err := json.Unmarshal(buf, &data); err != nil {
 			return nil, err
 		}
-		return NewMapConfigLoader(data, filepath, "json", "."), nil
+		return loadEncryptedConfiguration(data, filepath, "json", opts...)
 	}
 }
 
-func LoadYamlConfiguration(filepath string) (*MapConfigLoader, error) {
+func LoadYamlConfiguration(filepath string, opts ...LoaderOption) (*MapConfigLoader, error) {
 	if buf, err := readFile(filepath); err != nil {
 		return nil, err
 	} else {
@@ -272,19 +485,66 @@ func LoadYamlConfiguration(filepath string) (*MapConfigLoader, error) {
 		if err := yaml.Unmarshal(buf, &data); err != nil {
 			return nil, err
 		}
-		return NewMapConfigLoader(data, filepath, "yaml", "."), nil
+		return loadEncryptedConfiguration(data, filepath, "yaml", opts...)
 	}
 }
 
-func LoadEnvConfiguration() (*MapConfigLoader, error) {
+// LoadEnvConfiguration loads all environment variables, except for those
+// holding the encryption key (see EncryptionKeyEnv and EncryptionKeyFileEnv)
+func LoadEnvConfiguration(opts ...LoaderOption) (*MapConfigLoader, error) {
 	data := make(map[string]interface{})
 	for _, kv := range os.Environ() {
 		keys, value, _ := strings.Cut(kv, "=")
+		if keys == EncryptionKeyEnv || keys == EncryptionKeyFileEnv {
+			continue
+		}
 		if err := loadKvRecursive(data, strings.Split(keys, "."), value, []string{}); err != nil {
 			return nil, err
 		}
 	}
-	return NewMapConfigLoader(data, "environment variables", "env", "."), nil
+	return loadEncryptedConfiguration(data, "environment variables", "env", opts...)
+}
+
+// loadSettingsEnv loads the environment variables named by envKeys as the keys
+// they are mapped to
+func loadSettingsEnv(envKeys map[string]string, opts ...LoaderOption) (*MapConfigLoader, error) {
+	data := make(map[string]interface{})
+	for env, key := range envKeys {
+		if value, ok := os.LookupEnv(env); ok {
+			if err := loadKvRecursive(data, strings.Split(key, "."), value, []string{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	mcl, err := loadEncryptedConfiguration(data, "settings environment variables", "settings env", opts...)
+	if err != nil {
+		return nil, err
+	}
+	mcl.envKeys = envKeys
+	return mcl, nil
+}
+
+// loadEncryptedConfiguration decrypts the values within data and creates a
+// loader remembering which keys were encrypted
+func loadEncryptedConfiguration(data map[string]interface{}, filepath, configType string, opts ...LoaderOption) (*MapConfigLoader, error) {
+	decrypted, err := decryptValues(data)
+	if err != nil {
+		return nil, err
+	}
+	mcl, err := LoadMapConfiguration(data, filepath, configType, ".", opts...)
+	if err != nil {
+		return nil, err
+	}
+	mcl.encrypted = make(map[string]struct{}, len(decrypted))
+	for _, keys := range decrypted {
+		if mcl.normalize {
+			for i, k := range keys {
+				keys[i] = NormalizeKey(k)
+			}
+		}
+		mcl.encrypted[strings.Join(keys, mcl.sep)] = struct{}{}
+	}
+	return mcl, nil
 }
 func loadKvRecursive(m map[string]interface{}, keys []string, value string, trail []string) error {
 	if len(keys) > 1 {
@@ -324,6 +584,109 @@ func (jcl *MapConfigLoader) String() string {
 	return str
 }
 
+func (jcl *MapConfigLoader) getData() map[string]interface{} {
+	jcl.mu.RLock()
+	defer jcl.mu.RUnlock()
+	return jcl.data
+}
+
+// Reload reads the underlying file (or the environment) again and replaces
+// the loader's data if successful. Snapshots taken before are not affected.
+// Returns an error for loaders not created by one of the Load*Configuration
+// functions.
+func (jcl *MapConfigLoader) Reload() error {
+	var (
+		reloaded *MapConfigLoader
+		err      error
+	)
+	switch jcl.configType {
+	case "json":
+		reloaded, err = LoadJsonConfiguration(jcl.filepath, jcl.opts...)
+	case "yaml":
+		reloaded, err = LoadYamlConfiguration(jcl.filepath, jcl.opts...)
+	case "env":
+		reloaded, err = LoadEnvConfiguration(jcl.opts...)
+	case "settings env":
+		reloaded, err = loadSettingsEnv(jcl.envKeys, jcl.opts...)
+	case "defaults":
+		// defaults are taken from struct tags, which do not change
+		return nil
+	default:
+		err = errors.New("reloading is not supported by " + jcl.String())
+	}
+	if err != nil {
+		return err
+	}
+	jcl.mu.Lock()
+	defer jcl.mu.Unlock()
+	jcl.data = reloaded.data
+	jcl.encrypted = reloaded.encrypted
+	return nil
+}
+
+// Keys returns all keys holding a value (i.e. not a nested map, except for
+// empty ones), sorted
+func (jcl *MapConfigLoader) Keys() []string {
+	keys := make([]string, 0)
+	keys = jcl.collectKeys(jcl.getData(), "", keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func (jcl *MapConfigLoader) collectKeys(m map[string]interface{}, prefix string, keys []string) []string {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + jcl.sep + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			keys = jcl.collectKeys(nested, k, keys)
+		} else {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Snapshot returns an immutable view of the current configuration values.
+// Creating a snapshot is cheap as the underlying data is shared.
+func (jcl *MapConfigLoader) Snapshot() *Snapshot {
+	jcl.mu.RLock()
+	defer jcl.mu.RUnlock()
+	return &Snapshot{loader: &MapConfigLoader{
+		data:       jcl.data,
+		filepath:   jcl.filepath,
+		sep:        jcl.sep,
+		configType: jcl.configType,
+		normalize:  jcl.normalize,
+		encrypted:  jcl.encrypted,
+	}}
+}
+
+// IsEncryptedKey reports whether the value of key, or of any key below it,
+// was decrypted when loading
+func (jcl *MapConfigLoader) IsEncryptedKey(key string) bool {
+	jcl.mu.RLock()
+	encrypted := jcl.encrypted
+	jcl.mu.RUnlock()
+	key = strings.Join(jcl.splitKey(key), jcl.sep)
+	for k := range encrypted {
+		if k == key || strings.HasPrefix(k, key+jcl.sep) {
+			return true
+		}
+	}
+	return false
+}
+
+func (jcl *MapConfigLoader) splitKey(key string) []string {
+	keys := strings.Split(key, jcl.sep)
+	if jcl.normalize {
+		for i, k := range keys {
+			keys[i] = NormalizeKey(k)
+		}
+	}
+	return keys
+}
+
 func (jcl *MapConfigLoader) getTraverse(m map[string]interface{}, keys []string) (v interface{}, exists bool) {
 	if v, ok := m[keys[0]]; ok {
 		if len(keys) > 1 {
@@ -342,7 +705,7 @@ func (jcl *MapConfigLoader) getTraverse(m map[string]interface{}, keys []string)
 
 // Returns the value associated with key or nil
 func (jcl *MapConfigLoader) Get(key string) interface{} {
-	if value, exists := jcl.getTraverse(jcl.data, strings.Split(key, jcl.sep)); exists {
+	if value, exists := jcl.getTraverse(jcl.getData(), jcl.splitKey(key)); exists {
 		return value
 	} else {
 		return nil
@@ -379,7 +742,7 @@ func (jcl *MapConfigLoader) getTypeSafeExists(key string, ptrDest interface{}) (
 		retExists bool  = false
 		value     interface{}
 	)
-	if value, retExists = jcl.getTraverse(jcl.data, strings.Split(key, jcl.sep)); retExists {
+	if value, retExists = jcl.getTraverse(jcl.getData(), jcl.splitKey(key)); retExists {
 		vVal := reflect.ValueOf(value)
 		vRef := reflect.ValueOf(ptrDest).Elem()
 
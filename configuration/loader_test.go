@@ -1,10 +1,12 @@
 package configuration
 
 import (
+	"os"
 	"reflect"
 	"testing"
 
 	"github.com/ms-xy/go-common/stack"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadJsonConfiguration(t *testing.T) {
@@ -253,3 +255,91 @@ func TestLoadEnvConfiguration(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	filepath := dir + "/config.json"
+	if err := os.WriteFile(filepath, []byte(`{"version": "1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewCombinedLoader().MustLoadJSON(filepath)
+	snapshot := loader.Snapshot()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			loader.Get("version")
+			snapshot.Get("version")
+		}
+	}()
+
+	if err := os.WriteFile(filepath, []byte(`{"version": "2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertErrNil(t, "version", loader.Reload())
+	loader.MustLoadYaml("./loader_test.yaml")
+	<-done
+
+	assertEquals(t, "version", loader.Get("version"), "2")
+	assertEquals(t, "version", snapshot.Get("version"), "1")
+	assertEquals(t, "onlyInYaml", loader.Get("onlyInYaml"), "yamlTest")
+	assertEquals(t, "onlyInYaml", snapshot.Get("onlyInYaml"), nil)
+}
+
+func TestKeyNormalization(t *testing.T) {
+	loader := NewCombinedLoader(WithKeyNormalization())
+	loader.MustLoadYaml("./loader_test.yaml")
+
+	for _, key := range []string{"aMap.anInt", "AMAP.ANINT", "a-map.an-int", "a_map.an_int"} {
+		assertEquals(t, key, loader.Get(key), 123456)
+	}
+
+	_, err := LoadMapConfiguration(map[string]interface{}{
+		"db": map[string]interface{}{
+			"maxConns":  1,
+			"max_conns": 2,
+		},
+	}, "", "test", ".", WithKeyNormalization())
+	collision, ok := err.(WrappedError).Unwrap().(KeyCollisionError)
+	if !ok {
+		t.Fatalf("Expected a KeyCollisionError but got %v", err)
+	}
+	assertEquals(t, "collision", collision.Error(), "keys 'maxConns' and 'max_conns' in db both normalize to 'maxconns'")
+}
+
+func TestLoadSettings(t *testing.T) {
+	t.Setenv("DB_HOST", "db.example.com")
+	t.Setenv("db.timeout", "10s")
+
+	loader := NewCombinedLoader()
+	loader.addLoader(NewMapConfigLoader(map[string]interface{}{
+		"db": map[string]interface{}{"host": "localhost", "port": 6543},
+	}, "test", "map", "."))
+	require.Nil(t, loader.LoadSettings(&testSettings{}))
+	// layers loaded afterwards still take precedence over defaults
+	loader.addLoader(NewMapConfigLoader(map[string]interface{}{"name": "service"}, "test", "map", "."))
+
+	require.Equal(t, "db.example.com", loader.Get("db.host"))
+	require.Equal(t, 6543, loader.Get("db.port"))
+	require.Equal(t, "10s", loader.Get("db.timeout"))
+	require.Equal(t, "service", loader.Get("name"))
+	require.Nil(t, loader.Get("tags"))
+	var port int
+	require.Nil(t, loader.Snapshot().GetTypeSafe("db.port", &port))
+	require.Equal(t, 6543, port)
+
+	t.Setenv("DB_HOST", "replica.example.com")
+	// the map layers can not be reloaded, only the environment
+	require.Nil(t, loader.getLoaders()[0].(Reloader).Reload())
+	require.Equal(t, "replica.example.com", loader.Get("db.host"))
+
+	defaults := NewCombinedLoader()
+	require.Nil(t, defaults.LoadSettings(testSettings{}))
+	require.Equal(t, "app", defaults.Get("name"))
+	require.Equal(t, "5432", defaults.Get("db.port"))
+
+	os.Unsetenv("DB_HOST")
+	err := NewCombinedLoader().LoadSettings(testDbSettings{})
+	require.EqualError(t, err, "missing required configuration keys: host")
+}
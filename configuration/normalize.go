@@ -0,0 +1,83 @@
+package configuration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LoaderOption configures a MapConfigLoader, see LoadMapConfiguration and
+// NewCombinedLoader
+type LoaderOption func(*MapConfigLoader)
+
+// WithKeyNormalization makes key lookups insensitive to case, '-', '_' and
+// camelCase, so that "aMap.anInt", "A_MAP.AN_INT" and "a-map.an-int" all
+// resolve to the same value.
+//
+// Loading fails with a KeyCollisionError if two different keys of the same
+// map normalize to the same key.
+func WithKeyNormalization() LoaderOption {
+	return func(mcl *MapConfigLoader) {
+		mcl.normalize = true
+	}
+}
+
+// NormalizeKey returns the normalized form of a single key segment as used by
+// WithKeyNormalization
+func NormalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "-", "")
+	return strings.ReplaceAll(key, "_", "")
+}
+
+// KeyCollisionError is returned if two keys normalize to the same key
+type KeyCollisionError struct {
+	Path       string
+	Key        string
+	Other      string
+	Normalized string
+}
+
+func (e KeyCollisionError) Error() string {
+	where := ""
+	if e.Path != "" {
+		where = " in " + e.Path
+	}
+	return fmt.Sprintf("keys '%s' and '%s'%s both normalize to '%s'", e.Other, e.Key, where, e.Normalized)
+}
+
+// normalizeKeys returns a copy of m with all keys normalized, recursing into
+// nested maps
+func normalizeKeys(m map[string]interface{}, trail []string) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// sort for deterministic error messages
+	sort.Strings(keys)
+
+	normalized := make(map[string]interface{}, len(m))
+	originals := make(map[string]string, len(m))
+	for _, k := range keys {
+		nk := NormalizeKey(k)
+		if other, exists := originals[nk]; exists {
+			return nil, KeyCollisionError{
+				Path:       strings.Join(trail, "."),
+				Key:        k,
+				Other:      other,
+				Normalized: nk,
+			}
+		}
+		originals[nk] = k
+		if nested, ok := m[k].(map[string]interface{}); ok {
+			n, err := normalizeKeys(nested, append(trail, k))
+			if err != nil {
+				return nil, err
+			}
+			normalized[nk] = n
+		} else {
+			normalized[nk] = m[k]
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,98 @@
+package configuration
+
+import "fmt"
+
+// Snapshotter is implemented by loaders which can provide an immutable view of
+// their current configuration
+type Snapshotter interface {
+	Snapshot() *Snapshot
+}
+
+// Reloader is implemented by loaders which can re-read their source
+type Reloader interface {
+	Reload() error
+}
+
+// KeyLister is implemented by loaders which can enumerate their keys
+type KeyLister interface {
+	Keys() []string
+}
+
+// EncryptedKeyReporter is implemented by loaders which know whether the value
+// of a key was encrypted in their source
+type EncryptedKeyReporter interface {
+	IsEncryptedKey(key string) bool
+}
+
+// Snapshot is an immutable view of a configuration at the time it was taken.
+//
+// Snapshots are cheap to create and safe to share between goroutines, readers
+// may hold on to one while the originating loader reloads or loads further
+// layers.
+type Snapshot struct {
+	loader ConfigurationLoader
+}
+
+var _ ConfigurationLoader = (*Snapshot)(nil)
+var _ Snapshotter = (*Snapshot)(nil)
+
+// Snapshot returns the snapshot itself, as it is immutable already
+func (s *Snapshot) Snapshot() *Snapshot {
+	return s
+}
+
+// Keys returns the keys of the underlying loader, or nil if it does not
+// implement KeyLister
+func (s *Snapshot) Keys() []string {
+	if kl, ok := s.loader.(KeyLister); ok {
+		return kl.Keys()
+	}
+	return nil
+}
+
+// IsEncryptedKey reports whether the underlying loader decrypted the value of
+// key, false if it does not implement EncryptedKeyReporter
+func (s *Snapshot) IsEncryptedKey(key string) bool {
+	if r, ok := s.loader.(EncryptedKeyReporter); ok {
+		return r.IsEncryptedKey(key)
+	}
+	return false
+}
+
+// Returns the value associated with key or nil
+func (s *Snapshot) Get(key string) interface{} {
+	return s.loader.Get(key)
+}
+
+// Returns the value associated with key or the default value
+func (s *Snapshot) GetOrDefault(key string, defaultValue interface{}) interface{} {
+	return s.loader.GetOrDefault(key, defaultValue)
+}
+
+// Returns the value associated with key or panics
+func (s *Snapshot) Must(key string) interface{} {
+	return s.loader.Must(key)
+}
+
+// Writes the value found using key if - and only if - it either matches the
+// type of dest or if it is a string and can be unmarshelled to dest,
+// returns an error otherwise.
+// dest must be a pointer
+func (s *Snapshot) GetTypeSafe(key string, ptrDest interface{}) error {
+	return s.loader.GetTypeSafe(key, ptrDest)
+}
+
+// Same as GetTypeSafe, except it returns the default value if the key is
+// not present
+func (s *Snapshot) GetTypeSafeOrDefault(key string, ptrDest interface{}, defaultValue interface{}) error {
+	return s.loader.GetTypeSafeOrDefault(key, ptrDest, defaultValue)
+}
+
+// Print information about all contained config values
+func (s *Snapshot) DumpConfig() {
+	s.loader.DumpConfig()
+}
+
+func (s *Snapshot) String() string {
+	return "Snapshot[" + fmt.Sprint(s.loader) + "]"
+}
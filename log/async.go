@@ -0,0 +1,205 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what an AsyncHandler does with an entry if its queue
+// is full
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the entry being logged
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest queued entry to make room
+	DropOldest
+	// Block waits until there is room in the queue
+	Block
+)
+
+// Flusher is implemented by handlers buffering entries
+type Flusher interface {
+	// Flush blocks until all entries handled before have been written or ctx
+	// is done
+	Flush(ctx context.Context) error
+}
+
+// AsyncHandler queues entries and passes them to the wrapped handler from a
+// background goroutine, so logging does not block on slow writers.
+//
+// Call Close (or at least Flush) before exiting, otherwise queued entries are
+// lost. Fatal does so for all AsyncHandlers not closed yet.
+type AsyncHandler struct {
+	next    Handler
+	policy  OverflowPolicy
+	queue   chan *Entry
+	dropped uint64
+
+	closeMu sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+
+	pendingMu sync.Mutex
+	pending   int
+	idle      chan struct{}
+}
+
+var _ Handler = (*AsyncHandler)(nil)
+var _ Flusher = (*AsyncHandler)(nil)
+var _ io.Closer = (*AsyncHandler)(nil)
+
+// NewAsyncHandler starts a background goroutine passing entries to next.
+// At most queueSize entries are buffered, policy decides what happens beyond.
+func NewAsyncHandler(next Handler, queueSize int, policy OverflowPolicy) *AsyncHandler {
+	h := &AsyncHandler{
+		next:    next,
+		policy:  policy,
+		queue:   make(chan *Entry, queueSize),
+		stopped: make(chan struct{}),
+		idle:    make(chan struct{}),
+	}
+	close(h.idle)
+	go h.run()
+	closeOnExit(h)
+	return h
+}
+
+func (h *AsyncHandler) run() {
+	defer close(h.stopped)
+	for e := range h.queue {
+		h.handle(e)
+		h.done()
+	}
+}
+
+func (h *AsyncHandler) handle(e *Entry) {
+	if err := h.next.Handle(e); err != nil {
+		fmt.Fprintf(os.Stderr, "log: unable to handle entry: %v: %s\n", err, e.Message)
+	}
+}
+
+func (h *AsyncHandler) Enabled(level Level) bool {
+	return h.next.Enabled(level)
+}
+
+func (h *AsyncHandler) usesCaller() bool {
+	return usesCaller(h.next)
+}
+
+// Handle queues e according to the overflow policy. After Close entries are
+// passed to the wrapped handler synchronously.
+func (h *AsyncHandler) Handle(e *Entry) error {
+	h.closeMu.RLock()
+	defer h.closeMu.RUnlock()
+	if h.closed {
+		return h.next.Handle(e)
+	}
+
+	h.add()
+	switch h.policy {
+	case Block:
+		h.queue <- e
+	case DropOldest:
+		for {
+			select {
+			case h.queue <- e:
+				return nil
+			default:
+			}
+			select {
+			case <-h.queue:
+				atomic.AddUint64(&h.dropped, 1)
+				h.done()
+			default:
+			}
+		}
+	default:
+		select {
+		case h.queue <- e:
+		default:
+			atomic.AddUint64(&h.dropped, 1)
+			h.done()
+		}
+	}
+	return nil
+}
+
+func (h *AsyncHandler) add() {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	if h.pending == 0 {
+		h.idle = make(chan struct{})
+	}
+	h.pending++
+}
+
+func (h *AsyncHandler) done() {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	h.pending--
+	if h.pending == 0 {
+		close(h.idle)
+	}
+}
+
+// Dropped returns the number of entries discarded due to a full queue
+func (h *AsyncHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Flush waits until all entries queued so far have been handled, then flushes
+// the wrapped handler if it is a Flusher
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	h.pendingMu.Lock()
+	idle := h.idle
+	h.pendingMu.Unlock()
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if f, ok := h.next.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Close stops accepting new entries into the queue, waits for all queued
+// entries to be handled and closes the wrapped handler if it is an io.Closer
+func (h *AsyncHandler) Close() error {
+	forgetOnExit(h)
+	h.closeMu.Lock()
+	if h.closed {
+		h.closeMu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.queue)
+	h.closeMu.Unlock()
+
+	<-h.stopped
+	if c, ok := h.next.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// SetFormatter sets the formatter of the wrapped handler, if it supports it
+func (h *AsyncHandler) SetFormatter(formatter Formatter) {
+	if fs, ok := h.next.(formatterSetter); ok {
+		fs.SetFormatter(formatter)
+	}
+}
+
+// WithAsync wraps the logger's handler, as configured by the options given
+// before, in an AsyncHandler
+func WithAsync(queueSize int, policy OverflowPolicy) Option {
+	return func(l *Logger) {
+		l.handler.set(NewAsyncHandler(l.handler.get(), queueSize, policy))
+	}
+}
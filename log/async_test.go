@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHandler records messages and blocks until released
+type blockingHandler struct {
+	mu       sync.Mutex
+	messages []string
+	release  chan struct{}
+}
+
+func (h *blockingHandler) Enabled(level Level) bool {
+	return true
+}
+
+func (h *blockingHandler) Handle(e *Entry) error {
+	<-h.release
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, e.Message)
+	return nil
+}
+
+func (h *blockingHandler) Messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestAsyncHandlerDropPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy   OverflowPolicy
+		expected []string
+	}{
+		{DropNewest, []string{"0", "1", "2"}},
+		{DropOldest, []string{"0", "4", "5"}},
+	} {
+		next := &blockingHandler{release: make(chan struct{})}
+		h := NewAsyncHandler(next, 2, tc.policy)
+		l := New(LevelDebug, WithHandler(h))
+
+		l.Info("0")
+		// wait for the worker to pick up the first entry, so the queue is empty
+		for len(h.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for _, msg := range []string{"1", "2", "3", "4", "5"} {
+			l.Info(msg)
+		}
+		require.Equal(t, uint64(3), h.Dropped())
+
+		close(next.release)
+		require.Nil(t, l.Flush(context.Background()))
+		require.Equal(t, tc.expected, next.Messages())
+		require.Nil(t, l.Close())
+	}
+}
+
+func TestAsyncHandlerBlockAndClose(t *testing.T) {
+	next := &blockingHandler{release: make(chan struct{})}
+	l := New(LevelDebug, WithHandler(next), WithAsync(1, Block))
+	h := l.Handler().(*AsyncHandler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	l.Info("pending")
+	require.Equal(t, context.DeadlineExceeded, l.Flush(ctx))
+
+	close(next.release)
+	for i := 0; i < 100; i++ {
+		l.Info("entry")
+	}
+	require.Nil(t, l.Close())
+	require.Len(t, next.Messages(), 101)
+	require.Equal(t, uint64(0), h.Dropped())
+
+	// entries logged after Close are written synchronously
+	l.Info("late")
+	require.Len(t, next.Messages(), 102)
+}
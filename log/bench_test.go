@@ -0,0 +1,94 @@
+package log
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// Results on a single core Xeon VM before typed fields and the field cache
+// were added (WithField benchmarks only) and after:
+//
+//	                     before                       after
+//	DisabledWithField     1157 ns   887 B   8 allocs   1190 ns   935 B   8 allocs
+//	DisabledTypedFields                                  22 ns     0 B   0 allocs
+//	EnabledWithField     16675 ns  4496 B  46 allocs  17000 ns  3488 B  33 allocs
+//	EnabledTypedFields                                12000 ns  1904 B  21 allocs
+//	DeepChain            17142 ns  4584 B  42 allocs  12300 ns  1736 B  18 allocs
+//
+// WithField allocates slightly more than before, as loggers carry more state.
+// Logged entries allocate less, but are not faster, as they are dominated by
+// looking up the caller and formatting; only logging from long chains is.
+
+func benchLogger() *Logger {
+	return New(LevelInfo, WithHandler(NewSink(io.Discard, LevelDebug, &LogfmtFormatter{})))
+}
+
+func benchChain(l *Logger) *Logger {
+	for _, k := range []string{"service", "region", "host", "version", "request"} {
+		l = l.WithField(k, "value")
+	}
+	return l
+}
+
+func BenchmarkDisabledWithField(b *testing.B) {
+	l := benchLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithField("user", "jane").WithField("attempt", i).Debug("filtered")
+	}
+}
+
+func BenchmarkDisabledTypedFields(b *testing.B) {
+	l := benchLogger()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.DebugFields("filtered", String("user", "jane"), Int("attempt", i))
+	}
+}
+
+func BenchmarkEnabledWithField(b *testing.B) {
+	l := benchChain(benchLogger())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithField("user", "jane").WithField("took", time.Second).Info("logged")
+	}
+}
+
+func BenchmarkEnabledTypedFields(b *testing.B) {
+	l := benchChain(benchLogger())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.InfoFields("logged", String("user", "jane"), Dur("took", time.Second))
+	}
+}
+
+// BenchmarkDeepChain logs with a long chain of fields, which is flattened
+// only once
+func BenchmarkDeepChain(b *testing.B) {
+	l := benchChain(benchChain(benchLogger()).Named("bench"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Info("logged")
+	}
+}
+
+// BenchmarkDisabledRingBuffer logs a disabled level with a ring buffer
+// attached, which records it unless restricted by its level. On the same VM
+// this takes 6700 ns and 11 allocs per entry if recorded, 21 ns and no
+// allocs if not.
+func BenchmarkDisabledRingBuffer(b *testing.B) {
+	for _, level := range []Level{LevelDebug, LevelInfo} {
+		b.Run(level.String(), func(b *testing.B) {
+			rb := NewRingBuffer(100, io.Discard)
+			rb.SetLevel(level)
+			l := New(LevelInfo,
+				WithHandler(NewSink(io.Discard, LevelDebug, &LogfmtFormatter{})),
+				WithRingBuffer(rb))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.DebugFields("filtered", String("user", "jane"), Int("attempt", i))
+			}
+		})
+	}
+}
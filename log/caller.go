@@ -0,0 +1,176 @@
+package log
+
+import (
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CallerFormat decides how formatters render the caller of an entry
+type CallerFormat int
+
+const (
+	// CallerShort renders the file name and line, e.g. logger.go:12
+	CallerShort CallerFormat = iota
+	// CallerFullPath renders the absolute path of the file and the line
+	CallerFullPath
+	// CallerModule renders the path of the file relative to the main module
+	// (or the import path of its package for dependencies) and the line, e.g.
+	// log/logger.go:12
+	CallerModule
+	// CallerFunction renders the package qualified function name, e.g.
+	// log.(*Logger).Info
+	CallerFunction
+	// CallerOff omits the caller
+	CallerOff
+)
+
+var (
+	// logPackage is the import path of this package, its frames are skipped
+	// when determining the caller (except for those of tests)
+	logPackage = funcPackage(runtime.FuncForPC(reflect.ValueOf(New).Pointer()).Name())
+	// mainModule is the path of the main module, used by CallerModule
+	mainModule = func() string {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			return info.Main.Path
+		}
+		return ""
+	}()
+
+	// helpers holds the names of the functions marked by Helper
+	helpers sync.Map
+)
+
+// Helper marks the calling function as a logging helper, like
+// testing.T.Helper. When determining the caller of an entry, helper functions
+// are skipped, so the caller of the helper is reported instead. As with
+// testing.T.Helper, this does not extend to closures defined within the
+// helper (e.g. pkg.helper.func1), they have to call Helper themselves.
+func Helper() {
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			helpers.Store(fn.Name(), true)
+		}
+	}
+}
+
+// callerUser is implemented by handlers and formatters which can tell whether
+// they use the caller of entries. Others are assumed to use it.
+type callerUser interface {
+	usesCaller() bool
+}
+
+// usesCaller reports whether v, a Handler or Formatter, uses the caller
+func usesCaller(v interface{}) bool {
+	if u, ok := v.(callerUser); ok {
+		return u.usesCaller()
+	}
+	return true
+}
+
+// needsCaller reports whether the caller of an entry of the given level is
+// used by the handler, formatter override or ring buffer of the logger or by
+// a hook. Otherwise looking it up can be skipped.
+func (l *Logger) needsCaller(level Level) bool {
+	if l.records(level) || len(hooks.Load().([]*registeredHook)) > 0 {
+		return true
+	}
+	if formatter := l.formatter.get(); formatter != nil && usesCaller(formatter) {
+		return true
+	}
+	return usesCaller(l.handler.get())
+}
+
+// findCaller returns the first frame of the stack outside of this package,
+// helper functions and the given packages
+func findCaller(skipPackages map[string]bool) *runtime.Frame {
+	var buf [32]uintptr
+	pcs := buf[:]
+	for {
+		// skip runtime.Callers and findCaller
+		n := runtime.Callers(2, pcs)
+		frames := runtime.CallersFrames(pcs[:n])
+		for {
+			frame, more := frames.Next()
+			if !skipFrame(&frame, skipPackages) {
+				// copied, so that only the returned frame escapes to the heap
+				caller := frame
+				return &caller
+			}
+			if !more {
+				break
+			}
+		}
+		if n < len(pcs) {
+			return nil
+		}
+		// all frames in pcs were skipped, but the stack is deeper
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+}
+
+func skipFrame(frame *runtime.Frame, skipPackages map[string]bool) bool {
+	pkg := funcPackage(frame.Function)
+	if pkg == logPackage && !strings.HasSuffix(frame.File, "_test.go") {
+		return true
+	}
+	if skipPackages[pkg] {
+		return true
+	}
+	_, isHelper := helpers.Load(frame.Function)
+	return isHelper
+}
+
+// funcPackage returns the import path of the package of the fully qualified
+// function name, e.g. log for log.(*Logger).Printf
+func funcPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// callerLocation returns the location of the frame according to format and
+// its line, which is 0 if the format does not include it
+func callerLocation(frame *runtime.Frame, format CallerFormat) (string, int) {
+	switch format {
+	case CallerFullPath:
+		return frame.File, frame.Line
+	case CallerModule:
+		_, file := filepath.Split(frame.File)
+		pkg := funcPackage(frame.Function)
+		switch {
+		case pkg == "" || pkg == "main" || pkg == mainModule:
+			return file, frame.Line
+		case mainModule != "" && strings.HasPrefix(pkg, mainModule+"/"):
+			return path.Join(strings.TrimPrefix(pkg, mainModule+"/"), file), frame.Line
+		}
+		return path.Join(pkg, file), frame.Line
+	case CallerFunction:
+		return frame.Function[strings.LastIndex(frame.Function, "/")+1:], 0
+	}
+	_, file := filepath.Split(frame.File)
+	return file, frame.Line
+}
+
+// formatCaller renders the caller of e according to format, "<???>" if it is
+// unknown and an empty string for CallerOff
+func formatCaller(e *Entry, format CallerFormat) string {
+	if format == CallerOff {
+		return ""
+	}
+	if e.Caller == nil {
+		return "<???>"
+	}
+	location, line := callerLocation(e.Caller, format)
+	if line == 0 {
+		return location
+	}
+	return location + ":" + strconv.Itoa(line)
+}
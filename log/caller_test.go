@@ -0,0 +1,110 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// logVia is a logging helper, its caller is reported instead of itself
+func logVia(l *Logger, msg string) {
+	Helper()
+	l.Warn(msg)
+}
+
+func TestCaller(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+	defer SetHandler(defaultLogger.Handler())
+	SetHandler(l.Handler())
+
+	_, _, line, _ := runtime.Caller(0)
+	l.Info("method")
+	Info("package")
+	InfoCtx(context.Background(), "package ctx")
+	l.WithField("k", 1).InfoFields("fields")
+	logVia(l, "helper")
+	func() { l.Info("closure") }()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6)
+	for i, logged := range lines {
+		require.Contains(t, logged, fmt.Sprintf("caller=caller_test.go:%d", line+1+i))
+	}
+}
+
+// logDeep logs msg from depth nested helper calls
+func logDeep(l *Logger, depth int, msg string) {
+	Helper()
+	if depth == 0 {
+		l.Info(msg)
+		return
+	}
+	logDeep(l, depth-1, msg)
+}
+
+func TestCallerDeepStack(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	// more helper frames than findCaller looks at in one go
+	_, _, line, _ := runtime.Caller(0)
+	logDeep(l, 100, "deep")
+	require.Contains(t, buf.String(), fmt.Sprintf("caller=caller_test.go:%d msg=deep", line+1))
+}
+
+func TestNeedsCaller(t *testing.T) {
+	off := NewSink(io.Discard, LevelDebug, &LogfmtFormatter{Caller: CallerOff})
+	on := NewSink(io.Discard, LevelDebug, &JSONFormatter{})
+	syslog := &SyslogSink{}
+
+	require.False(t, New(LevelInfo, WithHandler(off)).needsCaller(LevelInfo))
+	require.False(t, New(LevelInfo, WithHandler(NewFanOutHandler(off, syslog))).needsCaller(LevelInfo))
+	require.True(t, New(LevelInfo, WithHandler(NewFanOutHandler(off, on))).needsCaller(LevelInfo))
+	require.True(t, New(LevelInfo, WithHandler(off), WithFormatter(&TextFormatter{})).needsCaller(LevelInfo))
+	// handlers of unknown types are assumed to use it
+	require.True(t, New(LevelInfo, WithHandler(handlerFunc(func(e *Entry) error { return nil }))).needsCaller(LevelInfo))
+
+	rb := NewRingBuffer(1, io.Discard)
+	rb.SetLevel(LevelWarn)
+	l := New(LevelInfo, WithHandler(off), WithRingBuffer(rb))
+	require.False(t, l.needsCaller(LevelInfo))
+	require.True(t, l.needsCaller(LevelWarn))
+
+	remove := AddHook(func(e *Entry) {})
+	defer remove()
+	require.True(t, New(LevelInfo, WithHandler(off)).needsCaller(LevelInfo))
+}
+
+func TestCallerFormat(t *testing.T) {
+	pc, file, line, _ := runtime.Caller(0)
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	frame.Line = line
+	e := testEntry()
+	e.Caller = &frame
+
+	location := fmt.Sprintf("caller_test.go:%d", line)
+	require.Equal(t, location, formatCaller(e, CallerShort))
+	require.Equal(t, fmt.Sprintf("%s:%d", file, line), formatCaller(e, CallerFullPath))
+	require.Equal(t, "log/"+location, formatCaller(e, CallerModule))
+	require.Equal(t, "log.TestCallerFormat", formatCaller(e, CallerFunction))
+	require.Equal(t, "", formatCaller(e, CallerOff))
+
+	buf, err := (&LogfmtFormatter{Caller: CallerOff}).Format(e)
+	require.Nil(t, err)
+	require.NotContains(t, string(buf), "caller=")
+
+	e.Name = "db"
+	buf, err = (&TextFormatter{ColorMode: ColorNever, Caller: CallerOff}).Format(e)
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(string(buf), "[2021-05-01T12:30:00Z] [WARN] db: a message\n"))
+	buf, err = (&TextFormatter{ColorMode: ColorNever, Caller: CallerFunction}).Format(e)
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(string(buf), "[2021-05-01T12:30:00Z] [WARN] db log.TestCallerFormat: a message\n"))
+}
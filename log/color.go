@@ -0,0 +1,71 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	color "gopkg.in/gookit/color.v1"
+)
+
+// ColorMode decides whether the TextFormatter emits color escape codes
+type ColorMode int
+
+const (
+	// ColorAuto colors the output if it is written to a terminal, unless
+	// overridden by the FORCE_COLOR or NO_COLOR environment variables
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output regardless of where it is written
+	ColorAlways
+	// ColorNever never emits color escape codes
+	ColorNever
+)
+
+// stderrIsTerminal is used by formatters not writing through a Sink
+var stderrIsTerminal = isTerminal(os.Stderr)
+
+// terminalFormatter is implemented by formatters whose output depends on
+// whether it is written to a terminal. Sinks pass whether their writer is one.
+type terminalFormatter interface {
+	formatTerminal(e *Entry, terminal bool) ([]byte, error)
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor decides whether to color output. In ColorAuto mode a non-empty
+// FORCE_COLOR (other than "0" or "false") enables colors, a non-empty NO_COLOR
+// disables them, otherwise colors are used for terminals only.
+func (m ColorMode) useColor(terminal bool) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if force := strings.ToLower(os.Getenv("FORCE_COLOR")); force != "" {
+		return force != "0" && force != "false"
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return terminal
+}
+
+// paint renders v using style if colored is set. Unlike style.Render it does
+// not depend on gookit's global color detection.
+func paint(style color.Style, colored bool, v interface{}) string {
+	s := fmt.Sprint(v)
+	if !colored || style.IsEmpty() {
+		return s
+	}
+	return fmt.Sprintf(color.FullColorTpl, style.String(), s)
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorMode(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+
+	plain := "[2021-05-01T12:30:00Z] [WARN] file.go:12: a message\n\terr=boom\n\tmsg=clash\n\tn=3\n\tuser=jane doe\n"
+
+	buf, err := (&TextFormatter{ColorMode: ColorNever}).Format(testEntry())
+	require.Nil(t, err)
+	require.Equal(t, plain, string(buf))
+
+	buf, err = (&TextFormatter{ColorMode: ColorAlways}).Format(testEntry())
+	require.Nil(t, err)
+	require.Contains(t, string(buf), "[\x1b[33mWARN\x1b[0m] file.go:\x1b[1m12\x1b[0m: a message")
+
+	// writers other than terminals are not colored in auto mode
+	out := new(bytes.Buffer)
+	require.Nil(t, NewSink(out, LevelDebug, nil).Handle(testEntry()))
+	require.Equal(t, plain, out.String())
+
+	t.Setenv("FORCE_COLOR", "1")
+	out.Reset()
+	require.Nil(t, NewSink(out, LevelDebug, nil).Handle(testEntry()))
+	require.True(t, strings.Contains(out.String(), "\x1b["))
+
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, ColorAuto.useColor(true))
+	require.True(t, ColorAlways.useColor(false))
+	t.Setenv("NO_COLOR", "")
+	require.True(t, ColorAuto.useColor(true))
+}
@@ -0,0 +1,191 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type loggerKey struct{}
+type fieldsKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, see FromContext
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext or the default
+// logger if there is none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return defaultLogger
+}
+
+// ContextWithField returns a copy of ctx carrying the given field in addition
+// to those already stored in ctx. The fields are added to every entry logged
+// using one of the *Ctx methods.
+func ContextWithField(ctx context.Context, key string, value interface{}) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, ContextFields(ctx).WithValue(key, value))
+}
+
+// ContextWithFields is the same as ContextWithField for multiple fields
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, ContextFields(ctx).WithValues(fields))
+}
+
+// ContextFields returns the fields stored in ctx
+func ContextFields(ctx context.Context) Context {
+	if fields, ok := ctx.Value(fieldsKey{}).(Context); ok {
+		return fields
+	}
+	return emptyContextImpl()
+}
+
+// WithContext returns a logger which adds the fields stored in ctx as well as
+// the deadline (ctx.deadline) and the cancellation cause (ctx.cause) of ctx to
+// every entry
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields := l.fields
+	if ctxFields, ok := ctx.Value(fieldsKey{}).(Context); ok {
+		fields = fields.WithValues(ctxFields.Map())
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		fields = fields.WithValue("ctx.deadline", deadline.Format(time.RFC3339Nano))
+	}
+	if ctx.Err() != nil {
+		fields = fields.WithValue("ctx.cause", fmt.Sprint(context.Cause(ctx)))
+	}
+	return l.derive(fields)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, msgs ...interface{}) {
+	if l.accepts(LevelDebug) {
+		l.WithContext(ctx).log(LevelDebug, msgs...)
+	}
+}
+
+func (l *Logger) DebugfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.accepts(LevelDebug) {
+		l.WithContext(ctx).log(LevelDebug, fmt.Sprintf(f, msgs...))
+	}
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, msgs ...interface{}) {
+	if l.accepts(LevelInfo) {
+		l.WithContext(ctx).log(LevelInfo, msgs...)
+	}
+}
+
+func (l *Logger) InfofCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.accepts(LevelInfo) {
+		l.WithContext(ctx).log(LevelInfo, fmt.Sprintf(f, msgs...))
+	}
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, msgs ...interface{}) {
+	if l.accepts(LevelWarn) {
+		l.WithContext(ctx).log(LevelWarn, msgs...)
+	}
+}
+
+func (l *Logger) WarnfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.accepts(LevelWarn) {
+		l.WithContext(ctx).log(LevelWarn, fmt.Sprintf(f, msgs...))
+	}
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msgs ...interface{}) {
+	if l.accepts(LevelError) {
+		l.WithContext(ctx).log(LevelError, msgs...)
+	}
+}
+
+func (l *Logger) ErrorfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.accepts(LevelError) {
+		l.WithContext(ctx).log(LevelError, fmt.Sprintf(f, msgs...))
+	}
+}
+
+func (l *Logger) PanicCtx(ctx context.Context, msgs ...interface{}) {
+	if l.Enabled(LevelPanic) {
+		l.crash(l.WithContext(ctx).log(LevelPanic, msgs...))
+	}
+}
+
+func (l *Logger) PanicfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.Enabled(LevelPanic) {
+		l.crash(l.WithContext(ctx).log(LevelPanic, fmt.Sprintf(f, msgs...)))
+	}
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, msgs ...interface{}) {
+	if l.Enabled(LevelFatal) {
+		l.WithContext(ctx).log(LevelFatal, msgs...)
+	}
+	l.exit()
+}
+
+func (l *Logger) FatalfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	if l.Enabled(LevelFatal) {
+		l.WithContext(ctx).log(LevelFatal, fmt.Sprintf(f, msgs...))
+	}
+	l.exit()
+}
+
+// DebugCtx logs using the logger stored in ctx (or the default logger)
+func DebugCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).DebugCtx(ctx, msgs...)
+}
+
+func DebugfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).DebugfCtx(ctx, f, msgs...)
+}
+
+// InfoCtx logs using the logger stored in ctx (or the default logger)
+func InfoCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).InfoCtx(ctx, msgs...)
+}
+
+func InfofCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).InfofCtx(ctx, f, msgs...)
+}
+
+// WarnCtx logs using the logger stored in ctx (or the default logger)
+func WarnCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).WarnCtx(ctx, msgs...)
+}
+
+func WarnfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).WarnfCtx(ctx, f, msgs...)
+}
+
+// ErrorCtx logs using the logger stored in ctx (or the default logger)
+func ErrorCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).ErrorCtx(ctx, msgs...)
+}
+
+func ErrorfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).ErrorfCtx(ctx, f, msgs...)
+}
+
+// PanicCtx logs using the logger stored in ctx (or the default logger), then
+// panics
+func PanicCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).PanicCtx(ctx, msgs...)
+}
+
+func PanicfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).PanicfCtx(ctx, f, msgs...)
+}
+
+// FatalCtx logs using the logger stored in ctx (or the default logger), then
+// exits, see Fatal
+func FatalCtx(ctx context.Context, msgs ...interface{}) {
+	FromContext(ctx).FatalCtx(ctx, msgs...)
+}
+
+func FatalfCtx(ctx context.Context, f string, msgs ...interface{}) {
+	FromContext(ctx).FatalfCtx(ctx, f, msgs...)
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextLogging(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	ctx := NewContext(context.Background(), l.WithField("service", "api"))
+	ctx = ContextWithField(ctx, "requestId", "r-1")
+	ctx = ContextWithFields(ctx, Fields{"userId": 7})
+	require.Equal(t, Fields{"requestId": "r-1", "userId": 7}, ContextFields(ctx).Map())
+
+	InfoCtx(ctx, "handled")
+	require.Contains(t, buf.String(), "caller=context_test.go:")
+	require.Contains(t, buf.String(), `msg=handled requestId=r-1 service=api userId=7`)
+
+	buf.Reset()
+	cancelled, cancel := context.WithCancelCause(ctx)
+	cancel(errors.New("client went away"))
+	FromContext(cancelled).WarnCtx(cancelled, "aborted")
+	require.Contains(t, buf.String(), `ctx.cause="client went away"`)
+
+	buf.Reset()
+	FromContext(ctx).DebugCtx(ctx, "filtered")
+	require.Equal(t, "", buf.String())
+}
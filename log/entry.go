@@ -0,0 +1,59 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Entry is a single log event as passed to a Formatter
+type Entry struct {
+	Time  time.Time
+	Level Level
+	// Name is the name of the logger, empty unless created using Named
+	Name    string
+	Caller  *runtime.Frame
+	Message string
+	Fields  Fields
+
+	// formatter overrides the formatter of the sink, see Logger.SetFormatter
+	formatter Formatter
+	// context holds the fields of the logger, fields those added on top of
+	// them (both are contained in Fields as well) and redaction the policy
+	// applied to Fields. They allow sinks like SlogSink to handle the fields
+	// of a logger once instead of with every entry.
+	context   Context
+	fields    []Field
+	redaction *RedactionPolicy
+}
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelPanic:
+		return "panic"
+	case LevelFatal:
+		return "fatal"
+	}
+	return "level(" + strconv.Itoa(int(level)) + ")"
+}
+
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+func (level *Level) UnmarshalText(text []byte) error {
+	l, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*level = l
+	return nil
+}
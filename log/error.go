@@ -0,0 +1,131 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ms-xy/go-common/stack"
+	"github.com/pkg/errors"
+)
+
+// captureErrorStacks is 1 if WithError captures stacks, see
+// SetCaptureErrorStacks
+var captureErrorStacks int32
+
+// SetCaptureErrorStacks makes WithError capture the stack at the call site if
+// the error does not carry a stack trace itself. Disabled by default, as
+// capturing reads the source files involved. It may be toggled at any time.
+func SetCaptureErrorStacks(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&captureErrorStacks, v)
+}
+
+// CaptureErrorStacks reports whether WithError captures stacks, see
+// SetCaptureErrorStacks
+func CaptureErrorStacks() bool {
+	return atomic.LoadInt32(&captureErrorStacks) == 1
+}
+
+// stackTracer is implemented by errors created by github.com/pkg/errors
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+// callersProvider is implemented by errors of various other packages which
+// record the program counters of the stack they were created on
+type callersProvider interface {
+	Callers() []uintptr
+}
+
+// WithError returns a logger adding the following fields for err:
+//
+//	error        the error message
+//	error.type   the type of err
+//	error.chain  type and message of err and every error it wraps
+//	error.stack  the stack trace of the innermost error carrying one (see
+//	             github.com/pkg/errors) or, if SetCaptureErrorStacks is on, the
+//	             stack at the call site
+func (l *Logger) WithError(err error) *Logger {
+	return l.WithFields(errorFields(err, 3))
+}
+
+// WithError returns a logger derived from the default logger, see
+// Logger.WithError
+func WithError(err error) *Logger {
+	return defaultLogger.WithFields(errorFields(err, 3))
+}
+
+// errorFields builds the fields of WithError, skip is passed to stack.Stack
+func errorFields(err error, skip int) Fields {
+	if err == nil {
+		return Fields{"error": nil}
+	}
+	chain := make([]string, 0, 1)
+	var trace string
+	walkErrorChain(err, func(e error) {
+		chain = append(chain, fmt.Sprintf("%T: %s", e, e.Error()))
+		// the innermost stack is closest to the origin of the error
+		if s := errorStack(e); s != "" {
+			trace = s
+		}
+	})
+
+	fields := Fields{
+		"error":       err.Error(),
+		"error.type":  fmt.Sprintf("%T", err),
+		"error.chain": chain,
+	}
+	if trace == "" && CaptureErrorStacks() {
+		trace = stack.Stack(skip)
+	}
+	if trace != "" {
+		fields["error.stack"] = trace
+	}
+	return fields
+}
+
+// walkErrorChain calls fn for err and all errors it wraps, depth first
+func walkErrorChain(err error, fn func(error)) {
+	fn(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if inner != nil {
+				walkErrorChain(inner, fn)
+			}
+		}
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			walkErrorChain(inner, fn)
+		}
+	case interface{ Cause() error }:
+		if inner := e.Cause(); inner != nil && inner != err {
+			walkErrorChain(inner, fn)
+		}
+	}
+}
+
+// errorStack renders the stack carried by err, if any
+func errorStack(err error) string {
+	switch e := err.(type) {
+	case stackTracer:
+		return strings.TrimPrefix(fmt.Sprintf("%+v", e.StackTrace()), "\n")
+	case callersProvider:
+		buf := new(strings.Builder)
+		frames := runtime.CallersFrames(e.Callers())
+		for {
+			frame, more := frames.Next()
+			fmt.Fprintf(buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			if !more {
+				break
+			}
+		}
+		return buf.String()
+	}
+	return ""
+}
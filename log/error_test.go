@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &JSONFormatter{})))
+
+	err := fmt.Errorf("reading config: %w", io.ErrUnexpectedEOF)
+	l.WithError(err).Error("failed")
+	record := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "reading config: unexpected EOF", record["error"])
+	require.Equal(t, "*fmt.wrapError", record["error.type"])
+	require.Equal(t, []interface{}{
+		"*fmt.wrapError: reading config: unexpected EOF",
+		"*errors.errorString: unexpected EOF",
+	}, record["error.chain"])
+	require.NotContains(t, record, "error.stack")
+
+	buf.Reset()
+	l.WithError(errors.Wrap(errors.New("boom"), "outer")).Error("failed")
+	record = make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Contains(t, record["error.stack"], "log.TestWithError")
+	require.Contains(t, record["error.stack"], "error_test.go")
+
+	SetCaptureErrorStacks(true)
+	defer SetCaptureErrorStacks(false)
+	fields := l.WithError(io.EOF).fields.Map()
+	require.Contains(t, fields["error.stack"], "error_test.go")
+	require.Contains(t, fields["error.stack"], "TestWithError")
+}
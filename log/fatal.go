@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var (
+	// ExitCode is the exit code used by Fatal and Fatalf
+	ExitCode = 1
+	// ExitFunc terminates the program after a fatal entry has been logged.
+	// Tests may replace it, Fatal returns if ExitFunc does.
+	ExitFunc = os.Exit
+	// ExitFlushTimeout limits how long Fatal waits for handlers to flush
+	ExitFlushTimeout = 5 * time.Second
+)
+
+var (
+	exitHooksMu sync.Mutex
+	exitHooks   []func()
+
+	// exitClosers holds the AsyncHandlers and RotatingFiles not closed yet,
+	// in the order they were created
+	exitClosersMu sync.Mutex
+	exitClosers   []io.Closer
+)
+
+// AddExitHook registers fn to be run by Fatal before the handlers are flushed
+// and the program exits. Hooks run in the reverse order of registration, a
+// panicking hook is recovered and reported on stderr.
+func AddExitHook(fn func()) {
+	exitHooksMu.Lock()
+	defer exitHooksMu.Unlock()
+	exitHooks = append(exitHooks, fn)
+}
+
+// closeOnExit registers c to be flushed and closed by Fatal, unless it is
+// closed before
+func closeOnExit(c io.Closer) {
+	exitClosersMu.Lock()
+	defer exitClosersMu.Unlock()
+	exitClosers = append(exitClosers, c)
+}
+
+// forgetOnExit removes c registered by closeOnExit
+func forgetOnExit(c io.Closer) {
+	exitClosersMu.Lock()
+	defer exitClosersMu.Unlock()
+	for i, other := range exitClosers {
+		if other == c {
+			exitClosers = append(exitClosers[:i:i], exitClosers[i+1:]...)
+			return
+		}
+	}
+}
+
+// exit dumps the ring buffer, runs the exit hooks, flushes and closes the
+// handlers of l and the default logger as well as all other AsyncHandlers and
+// RotatingFiles, then calls ExitFunc
+func (l *Logger) exit() {
+	if l.ring != nil {
+		l.ring.Dump()
+	}
+	exitHooksMu.Lock()
+	hooks := append([]func(){}, exitHooks...)
+	exitHooksMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runExitHook(hooks[i])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExitFlushTimeout)
+	defer cancel()
+	if err := FlushHooks(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "log: unable to flush hooks: %v\n", err)
+	}
+	closeHandler(ctx, l.Handler())
+	if !sameHandler(defaultLogger.Handler(), l.Handler()) {
+		closeHandler(ctx, defaultLogger.Handler())
+	}
+	// the handlers of other loggers, newest first, as handlers created later
+	// may write into files created before
+	exitClosersMu.Lock()
+	closers := append([]io.Closer(nil), exitClosers...)
+	exitClosersMu.Unlock()
+	for i := len(closers) - 1; i >= 0; i-- {
+		closeHandler(ctx, closers[i])
+	}
+	ExitFunc(ExitCode)
+}
+
+// sameHandler reports whether a and b are identical, handlers of an
+// uncomparable type (e.g. func types) are never considered identical
+func sameHandler(a, b Handler) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+func runExitHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "log: exit hook panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	fn()
+}
+
+// closeHandler flushes h if it is a Flusher and closes it if it is an
+// io.Closer, panics are recovered so the program exits regardless
+func closeHandler(ctx context.Context, h interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "log: flushing handler panicked: %v\n", r)
+		}
+	}()
+	if f, ok := h.(Flusher); ok {
+		if err := f.Flush(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "log: unable to flush handler: %v\n", err)
+		}
+	}
+	if c, ok := h.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "log: unable to close handler: %v\n", err)
+		}
+	}
+}
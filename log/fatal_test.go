@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFatal(t *testing.T) {
+	defer func(exitFunc func(int), code int) {
+		ExitFunc = exitFunc
+		ExitCode = code
+		exitHooks = nil
+	}(ExitFunc, ExitCode)
+
+	exitCode := -1
+	ExitFunc = func(code int) { exitCode = code }
+	ExitCode = 3
+
+	order := make([]string, 0)
+	AddExitHook(func() { order = append(order, "first") })
+	AddExitHook(func() { panic("broken exit hook") })
+	AddExitHook(func() { order = append(order, "last") })
+
+	buf := new(bytes.Buffer)
+	async := NewAsyncHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{}), 16, Block)
+	l := New(LevelError, WithHandler(async), WithSampling(1, 0, time.Minute))
+
+	l.Fatal("first")
+	l.Fatalf("second %d", 2)
+	require.Equal(t, 3, exitCode)
+	require.Equal(t, []string{"last", "first", "last", "first"}, order)
+	// the async handler has been flushed and closed, fatal entries are never
+	// sampled
+	require.Regexp(t, `level=fatal caller=fatal_test\.go:\d+ msg=first`, buf.String())
+	require.Contains(t, buf.String(), `msg="second 2"`)
+
+	level, err := ParseLevel("FATAL")
+	require.Nil(t, err)
+	require.Equal(t, LevelFatal, level)
+	require.Equal(t, "[PANIC]", getSeverity(LevelPanic, false))
+	require.Equal(t, "[FATAL]", getSeverity(LevelFatal, false))
+}
+
+// handlerFunc is a Handler of an uncomparable type
+type handlerFunc func(e *Entry) error
+
+func (f handlerFunc) Enabled(level Level) bool { return true }
+func (f handlerFunc) Handle(e *Entry) error    { return f(e) }
+
+func TestFatalUncomparableHandler(t *testing.T) {
+	defer func(exitFunc func(int), handler Handler) {
+		ExitFunc = exitFunc
+		SetHandler(handler)
+	}(ExitFunc, defaultLogger.Handler())
+
+	exited := false
+	ExitFunc = func(code int) { exited = true }
+	SetHandler(handlerFunc(func(e *Entry) error { return nil }))
+	l := New(LevelInfo, WithHandler(handlerFunc(func(e *Entry) error { return nil })))
+
+	require.NotPanics(t, func() { l.Fatal("bye") })
+	require.True(t, exited)
+}
+
+func TestFatalClosesOtherHandlers(t *testing.T) {
+	defer func(exitFunc func(int)) { ExitFunc = exitFunc }(ExitFunc)
+	ExitFunc = func(code int) {}
+
+	filename := filepath.Join(t.TempDir(), "other.log")
+	rf, err := NewRotatingFile(filename, RotateOptions{})
+	require.Nil(t, err)
+	release := make(chan struct{})
+	blocked := NewSink(writerFunc(func(p []byte) (int, error) {
+		<-release
+		return rf.Write(p)
+	}), LevelDebug, &LogfmtFormatter{})
+	other := New(LevelInfo, WithHandler(blocked), WithAsync(16, Block)).Named("other")
+	other.Info("buffered")
+	// the entry is still queued when Fatal starts
+	time.AfterFunc(20*time.Millisecond, func() { close(release) })
+
+	New(LevelInfo, WithHandler(NewSink(new(bytes.Buffer), LevelDebug, nil))).Fatal("bye")
+	content, err := os.ReadFile(filename)
+	require.Nil(t, err)
+	require.Contains(t, string(content), "msg=buffered")
+	// the file has been closed
+	require.Equal(t, os.ErrClosed, rf.Close())
+}
+
+// writerFunc is an io.Writer calling itself
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
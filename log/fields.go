@@ -0,0 +1,185 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type fieldType uint8
+
+const (
+	anyField fieldType = iota
+	stringField
+	intField
+	uintField
+	floatField
+	boolField
+	durationField
+	timeField
+)
+
+// Field is a typed key value pair. Unlike Fields, creating a Field does not
+// allocate, values are only converted once an entry is actually logged, so
+// entries filtered by level cost nothing. Logged entries still allocate, as
+// their fields are merged into Entry.Fields, redacted and formatted and their
+// caller is looked up. Use Logger.With and the level methods taking fields
+// (e.g. InfoFields) on hot paths.
+type Field struct {
+	Key string
+	typ fieldType
+	num int64
+	str string
+	val interface{}
+}
+
+// String creates a string field
+func String(key, value string) Field {
+	return Field{Key: key, typ: stringField, str: value}
+}
+
+// Int creates an int field
+func Int(key string, value int) Field {
+	return Field{Key: key, typ: intField, num: int64(value)}
+}
+
+// Int64 creates an int64 field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, typ: intField, num: value}
+}
+
+// Uint64 creates an uint64 field
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, typ: uintField, num: int64(value)}
+}
+
+// Float64 creates a float64 field
+func Float64(key string, value float64) Field {
+	return Field{Key: key, typ: floatField, num: int64(math.Float64bits(value))}
+}
+
+// Bool creates a bool field
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, typ: boolField}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// Dur creates a time.Duration field
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, typ: durationField, num: int64(value)}
+}
+
+// Time creates a time.Time field. The monotonic clock reading is dropped.
+func Time(key string, value time.Time) Field {
+	if value.IsZero() {
+		return Field{Key: key, typ: anyField, val: value}
+	}
+	return Field{Key: key, typ: timeField, num: value.UnixNano(), val: value.Location()}
+}
+
+// Err creates a field named "error" holding err (see WithError for details on
+// the error instead)
+func Err(err error) Field {
+	return Field{Key: "error", typ: anyField, val: err}
+}
+
+// Any creates a field of arbitrary type
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, typ: anyField, val: value}
+}
+
+// Value returns the value of the field as it is stored in Entry.Fields
+func (f Field) Value() interface{} {
+	switch f.typ {
+	case stringField:
+		return f.str
+	case intField:
+		return f.num
+	case uintField:
+		return uint64(f.num)
+	case floatField:
+		return math.Float64frombits(uint64(f.num))
+	case boolField:
+		return f.num == 1
+	case durationField:
+		return time.Duration(f.num)
+	case timeField:
+		return time.Unix(0, f.num).In(f.val.(*time.Location))
+	}
+	return f.val
+}
+
+func (f Field) String() string {
+	return f.Key + "=" + fmt.Sprint(f.Value())
+}
+
+// With returns a derived logger adding the given fields to all entries
+func (l *Logger) With(fields ...Field) *Logger {
+	m := make(Fields, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value()
+	}
+	return l.derive(l.fields.WithValues(m))
+}
+
+// With returns a logger derived from the default logger, see Logger.With
+func With(fields ...Field) *Logger {
+	return defaultLogger.With(fields...)
+}
+
+func (l *Logger) DebugFields(msg string, fields ...Field) {
+	if l.accepts(LevelDebug) {
+		l.logFields(LevelDebug, msg, fields)
+	}
+}
+
+func (l *Logger) InfoFields(msg string, fields ...Field) {
+	if l.accepts(LevelInfo) {
+		l.logFields(LevelInfo, msg, fields)
+	}
+}
+
+func (l *Logger) WarnFields(msg string, fields ...Field) {
+	if l.accepts(LevelWarn) {
+		l.logFields(LevelWarn, msg, fields)
+	}
+}
+
+func (l *Logger) ErrorFields(msg string, fields ...Field) {
+	if l.accepts(LevelError) {
+		l.logFields(LevelError, msg, fields)
+	}
+}
+
+func DebugFields(msg string, fields ...Field) {
+	defaultLogger.DebugFields(msg, fields...)
+}
+
+func InfoFields(msg string, fields ...Field) {
+	defaultLogger.InfoFields(msg, fields...)
+}
+
+func WarnFields(msg string, fields ...Field) {
+	defaultLogger.WarnFields(msg, fields...)
+}
+
+func ErrorFields(msg string, fields ...Field) {
+	defaultLogger.ErrorFields(msg, fields...)
+}
+
+// logFields is the equivalent of log for typed fields
+func (l *Logger) logFields(level Level, msg string, fields []Field) {
+	e := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Name:    l.Name(),
+		Message: msg,
+	}
+	if l.needsCaller(level) {
+		e.Caller = findCaller(nil)
+	}
+	l.emit(e, fields)
+}
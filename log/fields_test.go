@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedFields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	at := time.Date(2021, 5, 1, 12, 30, 0, 0, time.UTC)
+	l.With(String("user", "jane"), Int("attempt", 2)).InfoFields("login",
+		Float64("ratio", 0.5), Bool("ok", true), Dur("took", 1500*time.Millisecond),
+		Time("at", at), Err(errors.New("boom")), Uint64("id", 7), Any("tags", []string{"a"}))
+	require.Regexp(t, `caller=fields_test\.go:\d+ msg=login `+
+		`at="2021-05-01 12:30:00 \+0000 UTC" attempt=2 error=boom id=7 ok=true ratio=0\.5 tags=\[a\] took=1\.5s user=jane\n`, buf.String())
+
+	require.Equal(t, at, Time("at", at).Value())
+	require.Equal(t, time.Time{}, Time("at", time.Time{}).Value())
+	require.Equal(t, "n=-3", Int64("n", -3).String())
+
+	// filtered entries do not allocate
+	allocs := testing.AllocsPerRun(100, func() {
+		l.DebugFields("filtered", String("user", "jane"), Int("attempt", 2), Dur("took", time.Second))
+	})
+	require.Equal(t, 0.0, allocs)
+}
+
+func TestFieldCache(t *testing.T) {
+	l := New(LevelInfo).WithField("a", 1).WithFields(Fields{"b": 2}).With(Int("c", 3))
+	m := l.fields.Map()
+	require.Equal(t, Fields{"a": 1, "b": 2, "c": int64(3)}, m)
+
+	// the returned map is a copy
+	m["d"] = 4
+	require.Equal(t, Fields{"a": 1, "b": 2, "c": int64(3)}, l.fields.Map())
+
+	// so is the map passed to WithFields
+	fields := Fields{"e": 5}
+	child := l.WithFields(fields)
+	fields["e"] = 6
+	require.Equal(t, 5, child.fields.Map()["e"])
+}
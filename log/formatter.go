@@ -0,0 +1,268 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Formatter turns an entry into a single line (or, for the text format, a
+// block of lines) including the trailing newline
+type Formatter interface {
+	Format(e *Entry) ([]byte, error)
+}
+
+var (
+	_ Formatter = (*TextFormatter)(nil)
+	_ Formatter = (*JSONFormatter)(nil)
+	_ Formatter = (*LogfmtFormatter)(nil)
+)
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldKey prefixes field keys clashing with the keys used for the entry
+// itself in structured formats
+func fieldKey(key string) string {
+	switch key {
+	case "time", "level", "logger", "caller", "msg":
+		return "fields." + key
+	}
+	return key
+}
+
+// -------------------------------------------------------------------------- //
+
+// TextFormatter is the colored, human readable format:
+//
+//	[2006-01-02T15:04:05Z07:00] [INFO] name file.go:12: message
+//		key=value
+//
+// By default colors are used only if the output is a terminal, see ColorMode.
+type TextFormatter struct {
+	// TimeFormat defaults to time.RFC3339
+	TimeFormat string
+	// ColorMode defaults to ColorAuto
+	ColorMode ColorMode
+	// Caller defaults to CallerShort
+	Caller CallerFormat
+}
+
+var _ terminalFormatter = (*TextFormatter)(nil)
+
+func (f *TextFormatter) usesCaller() bool {
+	return f.Caller != CallerOff
+}
+
+// Format formats e, in ColorAuto mode colors are used if stderr is a terminal
+func (f *TextFormatter) Format(e *Entry) ([]byte, error) {
+	return f.formatTerminal(e, stderrIsTerminal)
+}
+
+func (f *TextFormatter) formatTerminal(e *Entry, terminal bool) ([]byte, error) {
+	colored := f.ColorMode.useColor(terminal)
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("[" + e.Time.Format(timeFormat) + "] ")
+	buf.WriteString(getSeverity(e.Level, colored) + " ")
+	switch {
+	case f.Caller == CallerOff:
+		if e.Name != "" {
+			buf.WriteString(e.Name + ": ")
+		}
+	case e.Caller == nil:
+		if e.Name != "" {
+			buf.WriteString(e.Name + " ")
+		}
+		buf.WriteString("<???>: ")
+	default:
+		if e.Name != "" {
+			buf.WriteString(e.Name + " ")
+		}
+		location, line := callerLocation(e.Caller, f.Caller)
+		buf.WriteString(paint(colorCaller, colored, location))
+		if line != 0 {
+			buf.WriteString(":" + paint(colorLine, colored, line))
+		}
+		buf.WriteString(": ")
+	}
+	buf.WriteString(e.Message)
+	for _, k := range sortedKeys(e.Fields) {
+		buf.WriteString("\n\t" + paint(colorFieldKey, colored, k) + "=" + paint(colorFieldValue, colored, e.Fields[k]))
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// -------------------------------------------------------------------------- //
+
+// JSONFormatter writes one JSON object per line. Fields are written as top
+// level keys, fields clashing with the keys time, level, logger, caller or msg
+// are prefixed with "fields.". The logger key is only present for named
+// loggers.
+//
+//	{"time":"2006-01-02T15:04:05Z","level":"info","caller":"file.go:12","msg":"a message","key":"value"}
+type JSONFormatter struct {
+	// TimeFormat defaults to time.RFC3339Nano
+	TimeFormat string
+	// Caller defaults to CallerShort, the caller key is omitted for CallerOff
+	Caller CallerFormat
+}
+
+func (f *JSONFormatter) usesCaller() bool {
+	return f.Caller != CallerOff
+}
+
+func (f *JSONFormatter) Format(e *Entry) ([]byte, error) {
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	writeJSONPair(buf, "time", e.Time.Format(timeFormat))
+	buf.WriteByte(',')
+	writeJSONPair(buf, "level", e.Level.String())
+	buf.WriteByte(',')
+	if e.Name != "" {
+		writeJSONPair(buf, "logger", e.Name)
+		buf.WriteByte(',')
+	}
+	if f.Caller != CallerOff {
+		writeJSONPair(buf, "caller", formatCaller(e, f.Caller))
+		buf.WriteByte(',')
+	}
+	writeJSONPair(buf, "msg", e.Message)
+	for _, k := range sortedKeys(e.Fields) {
+		buf.WriteByte(',')
+		writeJSONPair(buf, fieldKey(k), e.Fields[k])
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+func writeJSONPair(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(jsonValue(value))
+}
+
+// jsonValue marshals value, falling back to its string representation for
+// errors and values json can not handle
+func jsonValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case fmt.Stringer:
+		if _, isMarshaler := value.(json.Marshaler); !isMarshaler {
+			value = v.String()
+		}
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return b
+	}
+	b, _ := json.Marshal(fmt.Sprint(value))
+	return b
+}
+
+// -------------------------------------------------------------------------- //
+
+// LogfmtFormatter writes entries as key=value pairs (https://brandur.org/logfmt)
+//
+//	time=2006-01-02T15:04:05Z level=info caller=file.go:12 msg="a message" key=value
+type LogfmtFormatter struct {
+	// TimeFormat defaults to time.RFC3339Nano
+	TimeFormat string
+	// Caller defaults to CallerShort, the caller key is omitted for CallerOff
+	Caller CallerFormat
+}
+
+func (f *LogfmtFormatter) usesCaller() bool {
+	return f.Caller != CallerOff
+}
+
+func (f *LogfmtFormatter) Format(e *Entry) ([]byte, error) {
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+
+	buf := new(bytes.Buffer)
+	writeLogfmtPair(buf, "time", e.Time.Format(timeFormat))
+	buf.WriteByte(' ')
+	writeLogfmtPair(buf, "level", e.Level.String())
+	buf.WriteByte(' ')
+	if e.Name != "" {
+		writeLogfmtPair(buf, "logger", e.Name)
+		buf.WriteByte(' ')
+	}
+	if f.Caller != CallerOff {
+		writeLogfmtPair(buf, "caller", formatCaller(e, f.Caller))
+		buf.WriteByte(' ')
+	}
+	writeLogfmtPair(buf, "msg", e.Message)
+	for _, k := range sortedKeys(e.Fields) {
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, fieldKey(k), e.Fields[k])
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case error:
+		str = v.Error()
+	case nil:
+		str = ""
+	default:
+		str = fmt.Sprint(v)
+	}
+	if logfmtNeedsQuoting(str) {
+		str = strconv.Quote(str)
+	}
+	buf.WriteString(str)
+}
+
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtNeedsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry() *Entry {
+	return &Entry{
+		Time:    time.Date(2021, 5, 1, 12, 30, 0, 0, time.UTC),
+		Level:   LevelWarn,
+		Caller:  &runtime.Frame{File: "/src/pkg/file.go", Line: 12},
+		Message: "a message",
+		Fields: Fields{
+			"err":  errors.New("boom"),
+			"msg":  "clash",
+			"user": "jane doe",
+			"n":    3,
+		},
+	}
+}
+
+func TestJSONFormatter(t *testing.T) {
+	buf, err := (&JSONFormatter{}).Format(testEntry())
+	require.Nil(t, err)
+	require.Equal(t, `{"time":"2021-05-01T12:30:00Z","level":"warn","caller":"file.go:12","msg":"a message",`+
+		`"err":"boom","fields.msg":"clash","n":3,"user":"jane doe"}`+"\n", string(buf))
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	buf, err := (&LogfmtFormatter{}).Format(testEntry())
+	require.Nil(t, err)
+	require.Equal(t, `time=2021-05-01T12:30:00Z level=warn caller=file.go:12 msg="a message" `+
+		`err=boom fields.msg=clash n=3 user="jane doe"`+"\n", string(buf))
+}
@@ -0,0 +1,228 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Handler receives the entries emitted by a Logger
+type Handler interface {
+	// Enabled reports whether entries of the given level should be passed to
+	// Handle at all
+	Enabled(level Level) bool
+	// Handle processes a single entry, it must not modify the entry
+	Handle(e *Entry) error
+}
+
+// formatterSetter is implemented by handlers whose formatter can be replaced
+type formatterSetter interface {
+	SetFormatter(formatter Formatter)
+}
+
+// -------------------------------------------------------------------------- //
+
+// Sink is a Handler formatting entries of at least its level and writing them
+// to an io.Writer. Writes are serialized, so a Sink may be shared by any
+// number of loggers.
+type Sink struct {
+	mu        sync.Mutex
+	w         io.Writer
+	terminal  bool
+	level     Level
+	formatter Formatter
+}
+
+var _ Handler = (*Sink)(nil)
+
+// NewSink creates a sink writing entries of at least level to w.
+// If formatter is nil the TextFormatter is used, which colors its output if w
+// is a terminal (see ColorMode).
+func NewSink(w io.Writer, level Level, formatter Formatter) *Sink {
+	if formatter == nil {
+		formatter = &TextFormatter{}
+	}
+	return &Sink{
+		w:         w,
+		terminal:  isTerminal(w),
+		level:     level,
+		formatter: formatter,
+	}
+}
+
+func (s *Sink) Enabled(level Level) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return level >= s.level
+}
+
+func (s *Sink) Handle(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	formatter := s.formatter
+	if e.formatter != nil {
+		formatter = e.formatter
+	}
+	var buf []byte
+	var err error
+	if tf, ok := formatter.(terminalFormatter); ok {
+		buf, err = tf.formatTerminal(e, s.terminal)
+	} else {
+		buf, err = formatter.Format(e)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(buf)
+	return err
+}
+
+func (s *Sink) usesCaller() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return usesCaller(s.formatter)
+}
+
+// Flush flushes the underlying writer if it buffers data (e.g. a
+// *bufio.Writer)
+func (s *Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// SetLevel changes the minimum level of entries written by the sink
+func (s *Sink) SetLevel(level Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.level = level
+}
+
+// SetFormatter changes the formatter of the sink for all loggers using it,
+// except for those overriding it (see Logger.SetFormatter)
+func (s *Sink) SetFormatter(formatter Formatter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.formatter = formatter
+}
+
+// -------------------------------------------------------------------------- //
+
+// FanOutHandler passes every entry to all of its handlers which are enabled
+// for the entry's level
+type FanOutHandler struct {
+	handlers []Handler
+}
+
+var _ Handler = (*FanOutHandler)(nil)
+
+func NewFanOutHandler(handlers ...Handler) *FanOutHandler {
+	return &FanOutHandler{handlers: handlers}
+}
+
+// Handlers returns the handlers entries are distributed to
+func (h *FanOutHandler) Handlers() []Handler {
+	return h.handlers
+}
+
+func (h *FanOutHandler) Enabled(level Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FanOutHandler) usesCaller() bool {
+	for _, handler := range h.handlers {
+		if usesCaller(handler) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes e to all enabled handlers, even if some of them fail.
+// The returned error combines all errors encountered.
+func (h *FanOutHandler) Handle(e *Entry) error {
+	return h.each(func(handler Handler) error {
+		if handler.Enabled(e.Level) {
+			return handler.Handle(e)
+		}
+		return nil
+	})
+}
+
+// Flush flushes all handlers implementing Flusher
+func (h *FanOutHandler) Flush(ctx context.Context) error {
+	return h.each(func(handler Handler) error {
+		if f, ok := handler.(Flusher); ok {
+			return f.Flush(ctx)
+		}
+		return nil
+	})
+}
+
+// Close closes all handlers implementing io.Closer
+func (h *FanOutHandler) Close() error {
+	return h.each(func(handler Handler) error {
+		if c, ok := handler.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	})
+}
+
+func (h *FanOutHandler) each(fn func(handler Handler) error) error {
+	_errors := make([]string, 0)
+	for _, handler := range h.handlers {
+		if err := fn(handler); err != nil {
+			_errors = append(_errors, err.Error())
+		}
+	}
+	if len(_errors) > 0 {
+		return errors.New(strings.Join(_errors, "; "))
+	}
+	return nil
+}
+
+// SetFormatter sets the formatter of all handlers supporting it
+func (h *FanOutHandler) SetFormatter(formatter Formatter) {
+	for _, handler := range h.handlers {
+		if fs, ok := handler.(formatterSetter); ok {
+			fs.SetFormatter(formatter)
+		}
+	}
+}
+
+// -------------------------------------------------------------------------- //
+
+// Option configures a Logger created by New
+type Option func(*Logger)
+
+// WithHandler replaces the default stderr sink of the logger with h
+func WithHandler(h Handler) Option {
+	return func(l *Logger) {
+		l.handler.set(h)
+	}
+}
+
+// WithSinks replaces the default stderr sink with a FanOutHandler passing
+// entries to all given handlers
+func WithSinks(handlers ...Handler) Option {
+	return WithHandler(NewFanOutHandler(handlers...))
+}
+
+// WithFormatter sets the formatter used by the logger's sinks, regardless of
+// the order of the options, see Logger.SetFormatter
+func WithFormatter(formatter Formatter) Option {
+	return func(l *Logger) {
+		l.SetFormatter(formatter)
+	}
+}
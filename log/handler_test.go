@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOutHandler(t *testing.T) {
+	jsonBuf := new(bytes.Buffer)
+	logfmtBuf := new(bytes.Buffer)
+	l := New(LevelDebug, WithSinks(
+		NewSink(jsonBuf, LevelWarn, &JSONFormatter{}),
+		NewSink(logfmtBuf, LevelDebug, &LogfmtFormatter{}),
+	))
+	child := l.WithField("request", 42)
+	require.Same(t, l.Handler(), child.Handler())
+
+	l.Info("started")
+	child.Warn("slow request")
+
+	jsonLines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	require.Len(t, jsonLines, 1)
+	require.Contains(t, jsonLines[0], `"level":"warn"`)
+	require.Contains(t, jsonLines[0], `"request":42`)
+
+	logfmtLines := strings.Split(strings.TrimSpace(logfmtBuf.String()), "\n")
+	require.Len(t, logfmtLines, 2)
+	require.Contains(t, logfmtLines[0], `msg=started`)
+	require.Contains(t, logfmtLines[1], `msg="slow request" request=42`)
+}
+
+func TestLoggerFormatter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sink := NewSink(buf, LevelDebug, &LogfmtFormatter{Caller: CallerOff})
+	jsonLogger := New(LevelDebug, WithFormatter(&JSONFormatter{Caller: CallerOff}), WithHandler(sink))
+	child := jsonLogger.WithField("request", 42)
+	plain := New(LevelDebug, WithHandler(sink))
+
+	jsonLogger.Info("json")
+	child.Info("child")
+	plain.Info("logfmt")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], `"msg":"json"`)
+	require.Contains(t, lines[1], `"request":42`)
+	require.Contains(t, lines[2], `msg=logfmt`)
+
+	buf.Reset()
+	jsonLogger.SetFormatter(nil)
+	child.Info("child")
+	require.Contains(t, buf.String(), `msg=child request=42`)
+}
+
+func TestSetHandler(t *testing.T) {
+	first := new(bytes.Buffer)
+	second := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(first, LevelDebug, &LogfmtFormatter{})))
+	derived := l.WithField("k", "v")
+	named := l.Named("db")
+
+	l.SetHandler(NewSink(second, LevelDebug, &LogfmtFormatter{}))
+	derived.Info("derived")
+	named.Info("named")
+	l.WithField("k", "v").Info("later")
+	require.Equal(t, "", first.String())
+	require.Equal(t, 3, strings.Count(second.String(), "\n"))
+
+	// replacing the handler while logging is safe
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			derived.Info("concurrent")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.SetHandler(NewFanOutHandler(NewSink(io.Discard, LevelDebug, nil)))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,259 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hookRemoveTimeout limits how long removing an asynchronous hook waits for
+// its queued entries to be processed
+const hookRemoveTimeout = 5 * time.Second
+
+// Hook is called for every entry passed to a handler by any logger. Hooks
+// must not modify the entry.
+type Hook func(e *Entry)
+
+// HookOption configures a hook registered with AddHook
+type HookOption func(*registeredHook)
+
+// HookLevels restricts a hook to entries of the given levels
+func HookLevels(levels ...Level) HookOption {
+	return func(h *registeredHook) {
+		h.levels = make(map[Level]bool, len(levels))
+		for _, level := range levels {
+			h.levels[level] = true
+		}
+	}
+}
+
+// HookMinLevel restricts a hook to entries of at least the given level
+func HookMinLevel(level Level) HookOption {
+	return func(h *registeredHook) {
+		h.minLevel = level
+	}
+}
+
+// HookAsync runs the hook in a background goroutine, so slow hooks do not
+// block logging. At most queueSize entries are buffered, further entries are
+// dropped until the hook catches up (see HookDropped).
+func HookAsync(queueSize int) HookOption {
+	return func(h *registeredHook) {
+		h.queue = make(chan *Entry, queueSize)
+	}
+}
+
+type registeredHook struct {
+	hook     Hook
+	levels   map[Level]bool
+	minLevel Level
+	queue    chan *Entry
+	stop     chan struct{}
+
+	// sendMu is held while queueing an entry, so that none is queued once
+	// the hook is stopped
+	sendMu  sync.RWMutex
+	stopped bool
+
+	pendingMu sync.Mutex
+	pending   int
+	idle      chan struct{}
+}
+
+// hooks holds the []*registeredHook currently registered, it is replaced on
+// every change so dispatching entries does not need a lock
+var (
+	hooks   atomic.Value
+	hooksMu sync.Mutex
+	// hooksDropped counts the entries dropped by asynchronous hooks
+	hooksDropped uint64
+)
+
+func init() {
+	hooks.Store([]*registeredHook(nil))
+}
+
+// AddHook registers hook for the entries of all loggers and returns a function
+// removing it again. By default hooks run synchronously for all levels,
+// before the logging call returns. A panicking hook is recovered and reported
+// on stderr.
+//
+// Removing an asynchronous hook waits for the entries queued so far to be
+// processed, for at most 5 seconds.
+func AddHook(hook Hook, opts ...HookOption) (remove func()) {
+	h := &registeredHook{hook: hook, idle: make(chan struct{})}
+	close(h.idle)
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.queue != nil {
+		h.stop = make(chan struct{})
+		go h.run()
+	}
+
+	hooksMu.Lock()
+	current := hooks.Load().([]*registeredHook)
+	hooks.Store(append(append(make([]*registeredHook, 0, len(current)+1), current...), h))
+	hooksMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			hooksMu.Lock()
+			current := hooks.Load().([]*registeredHook)
+			remaining := make([]*registeredHook, 0, len(current))
+			for _, other := range current {
+				if other != h {
+					remaining = append(remaining, other)
+				}
+			}
+			hooks.Store(remaining)
+			hooksMu.Unlock()
+			if h.queue != nil {
+				h.pendingMu.Lock()
+				idle := h.idle
+				h.pendingMu.Unlock()
+				select {
+				case <-idle:
+				case <-time.After(hookRemoveTimeout):
+				}
+				// the queue is not closed, as runHooks may still hold h, but
+				// nothing is queued after stopped is set
+				h.sendMu.Lock()
+				h.stopped = true
+				h.sendMu.Unlock()
+				close(h.stop)
+			}
+		})
+	}
+}
+
+// HookDropped returns the number of entries asynchronous hooks dropped due to
+// a full queue
+func HookDropped() uint64 {
+	return atomic.LoadUint64(&hooksDropped)
+}
+
+// FlushHooks waits until all asynchronous hooks have processed the entries
+// queued so far or ctx is done
+func FlushHooks(ctx context.Context) error {
+	for _, h := range hooks.Load().([]*registeredHook) {
+		h.pendingMu.Lock()
+		idle := h.idle
+		h.pendingMu.Unlock()
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// runHooks passes e to all registered hooks interested in its level
+func runHooks(e *Entry) {
+	for _, h := range hooks.Load().([]*registeredHook) {
+		if e.Level < h.minLevel || (h.levels != nil && !h.levels[e.Level]) {
+			continue
+		}
+		if h.queue == nil {
+			h.call(e)
+			continue
+		}
+		h.queueEntry(e)
+	}
+}
+
+// queueEntry passes e to the worker of an asynchronous hook, unless the hook
+// has been removed meanwhile
+func (h *registeredHook) queueEntry(e *Entry) {
+	h.sendMu.RLock()
+	defer h.sendMu.RUnlock()
+	if h.stopped {
+		return
+	}
+	h.add()
+	select {
+	case h.queue <- e:
+	default:
+		atomic.AddUint64(&hooksDropped, 1)
+		h.done()
+	}
+}
+
+func (h *registeredHook) run() {
+	for {
+		select {
+		case e := <-h.queue:
+			h.call(e)
+			h.done()
+		case <-h.stop:
+			// entries queued before the hook was stopped are still processed
+			for {
+				select {
+				case e := <-h.queue:
+					h.call(e)
+					h.done()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// call runs the hook, isolating the caller from panics
+func (h *registeredHook) call(e *Entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "log: hook panicked: %v: %s\n%s", r, e.Message, debug.Stack())
+		}
+	}()
+	h.hook(e)
+}
+
+func (h *registeredHook) add() {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	if h.pending == 0 {
+		h.idle = make(chan struct{})
+	}
+	h.pending++
+}
+
+func (h *registeredHook) done() {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	h.pending--
+	if h.pending == 0 {
+		close(h.idle)
+	}
+}
+
+// -------------------------------------------------------------------------- //
+
+// LevelCounter counts entries per level, register its Hook using AddHook
+type LevelCounter struct {
+	counts [LevelFatal + 1]uint64
+}
+
+// Hook returns the hook incrementing the counters
+func (c *LevelCounter) Hook() Hook {
+	return func(e *Entry) {
+		if e.Level >= 0 && int(e.Level) < len(c.counts) {
+			atomic.AddUint64(&c.counts[e.Level], 1)
+		}
+	}
+}
+
+// Count returns the number of entries of the given level counted so far
+func (c *LevelCounter) Count(level Level) uint64 {
+	if level < 0 || int(level) >= len(c.counts) {
+		return 0
+	}
+	return atomic.LoadUint64(&c.counts[level])
+}
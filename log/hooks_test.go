@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelDebug, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	counter := new(LevelCounter)
+	removeCounter := AddHook(counter.Hook())
+	defer removeCounter()
+
+	var alertsMu sync.Mutex
+	alerts := make([]string, 0)
+	removeAlerts := AddHook(func(e *Entry) {
+		alertsMu.Lock()
+		defer alertsMu.Unlock()
+		alerts = append(alerts, e.Message+" "+e.Fields["user"].(string))
+	}, HookMinLevel(LevelError), HookAsync(16))
+	defer removeAlerts()
+
+	removePanicking := AddHook(func(e *Entry) {
+		panic("broken hook")
+	}, HookLevels(LevelWarn))
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.WithField("user", "jane").Error("login failed")
+	require.Panics(t, func() { l.WithField("user", "joe").Panic("out of memory") })
+
+	require.NoError(t, FlushHooks(context.Background()))
+	require.Equal(t, uint64(1), counter.Count(LevelDebug))
+	require.Equal(t, uint64(1), counter.Count(LevelWarn))
+	require.Equal(t, uint64(1), counter.Count(LevelPanic))
+	alertsMu.Lock()
+	require.Equal(t, []string{"login failed jane", "out of memory joe"}, alerts)
+	alertsMu.Unlock()
+	// the panicking hook did not keep the entry from being written
+	require.Contains(t, buf.String(), "msg=warn")
+
+	removePanicking()
+	removeCounter()
+	l.Warn("warn")
+	require.Equal(t, uint64(1), counter.Count(LevelWarn))
+}
+
+func TestHookAsyncDoesNotBlock(t *testing.T) {
+	l := New(LevelDebug, WithHandler(NewSink(new(bytes.Buffer), LevelDebug, &LogfmtFormatter{})))
+	release := make(chan struct{})
+	remove := AddHook(func(e *Entry) { <-release }, HookAsync(1))
+	defer remove()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			l.Info("entry")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging blocked on an asynchronous hook")
+	}
+	close(release)
+	require.NoError(t, FlushHooks(context.Background()))
+}
+
+func TestRemoveAsyncHookDrainsQueue(t *testing.T) {
+	l := New(LevelDebug, WithHandler(NewSink(new(bytes.Buffer), LevelDebug, &LogfmtFormatter{})))
+	var processed int32
+	remove := AddHook(func(e *Entry) {
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&processed, 1)
+	}, HookAsync(32))
+
+	for i := 0; i < 20; i++ {
+		l.Info("entry")
+	}
+	remove()
+	require.Equal(t, int32(20), atomic.LoadInt32(&processed))
+}
+
+func TestAsyncHookDropped(t *testing.T) {
+	l := New(LevelDebug, WithHandler(NewSink(new(bytes.Buffer), LevelDebug, &LogfmtFormatter{})))
+	release := make(chan struct{})
+	remove := AddHook(func(e *Entry) { <-release }, HookAsync(1))
+	dropped := HookDropped()
+
+	// the first entry blocks the hook, the second one is queued
+	l.Info("processed")
+	require.Eventually(t, func() bool {
+		l.Info("queued or dropped")
+		return HookDropped() > dropped
+	}, time.Second, time.Millisecond)
+	close(release)
+	remove()
+}
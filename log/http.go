@@ -0,0 +1,155 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler is an http.Handler to inspect and change log levels at
+// runtime.
+//
+// GET returns the current levels, including the effective level of every
+// named logger created so far:
+//
+//	{"level":"info","overrides":{"db.*":"debug"},"loggers":{"db":"info","db.transactions":"debug"}}
+//
+// PUT or POST change the level of the logger (if "logger" is omitted), which
+// named loggers derived from it follow, or the level override for a pattern
+// (see SetLevelOverrides). If a ttl is given the
+// previous level is restored after it has passed:
+//
+//	{"logger":"db.*","level":"debug","ttl":"10m"}
+type LevelHandler struct {
+	logger *Logger
+
+	mu      sync.Mutex
+	reverts map[string]*levelRevert
+}
+
+var _ http.Handler = (*LevelHandler)(nil)
+
+// maxLevelChangeSize limits the size of a request body
+const maxLevelChangeSize = 4096
+
+// levelRevert restores the level of a logger or pattern once its timer fires
+type levelRevert struct {
+	timer   *time.Timer
+	restore func()
+}
+
+type levelState struct {
+	Level     Level            `json:"level"`
+	Overrides map[string]Level `json:"overrides"`
+	Loggers   map[string]Level `json:"loggers"`
+}
+
+type levelChange struct {
+	Logger string `json:"logger"`
+	Level  *Level `json:"level"`
+	TTL    string `json:"ttl"`
+}
+
+// NewLevelHandler creates a handler controlling the level of logger (the
+// default logger if nil) and the level overrides of named loggers
+func NewLevelHandler(logger *Logger) *LevelHandler {
+	return &LevelHandler{
+		logger:  logger,
+		reverts: make(map[string]*levelRevert),
+	}
+}
+
+func (h *LevelHandler) target() *Logger {
+	if h.logger == nil {
+		return defaultLogger
+	}
+	return h.logger
+}
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var change levelChange
+		body := http.MaxBytesReader(w, r.Body, maxLevelChangeSize)
+		if err := json.NewDecoder(body).Decode(&change); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if change.Level == nil {
+			http.Error(w, "invalid request: level is required", http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if change.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(change.TTL); err != nil || ttl <= 0 {
+				http.Error(w, "invalid request: invalid ttl '"+change.TTL+"'", http.StatusBadRequest)
+				return
+			}
+		}
+		h.apply(change.Logger, *change.Level, ttl)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levelState{
+		Level:     h.target().Level(),
+		Overrides: LevelOverrides(),
+		Loggers:   registry.levels(h.target().Level()),
+	})
+}
+
+// apply changes the level of the logger (pattern == "") or the override for
+// pattern, scheduling a revert if ttl > 0
+func (h *LevelHandler) apply(pattern string, level Level, ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// a pending revert keeps restoring the original level
+	var restore func()
+	if revert, pending := h.reverts[pattern]; pending {
+		revert.timer.Stop()
+		delete(h.reverts, pattern)
+		restore = revert.restore
+	} else if ttl > 0 {
+		restore = h.restoreFunc(pattern)
+	}
+
+	if pattern == "" {
+		h.target().SetLevel(level)
+	} else {
+		SetLevelOverride(pattern, level)
+	}
+
+	if ttl > 0 {
+		// every timer gets its own revert, so a timer which fired already but
+		// is still waiting for h.mu can not match the revert scheduled since
+		revert := &levelRevert{restore: restore}
+		h.reverts[pattern] = revert
+		revert.timer = time.AfterFunc(ttl, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.reverts[pattern] == revert {
+				delete(h.reverts, pattern)
+				revert.restore()
+			}
+		})
+	}
+}
+
+// restoreFunc captures the current level of the logger or pattern
+func (h *LevelHandler) restoreFunc(pattern string) func() {
+	if pattern == "" {
+		level := h.target().Level()
+		return func() { h.target().SetLevel(level) }
+	}
+	if level, exists := LevelOverrides()[pattern]; exists {
+		return func() { SetLevelOverride(pattern, level) }
+	}
+	return func() { RemoveLevelOverride(pattern) }
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevelOverrides("")
+	defer SetLevel(defaultLogger.Level())
+	defaultLogger.SetLevel(LevelInfo)
+	db := Named("db")
+	h := NewLevelHandler(nil)
+
+	request := func(method, body string) (int, levelState) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/loglevel", strings.NewReader(body)))
+		var state levelState
+		if rec.Code == http.StatusOK {
+			require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &state))
+		}
+		return rec.Code, state
+	}
+
+	code, state := request(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, LevelInfo, state.Level)
+
+	code, state = request(http.MethodPut, `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, LevelDebug, state.Level)
+	// named loggers created before follow the level
+	require.Equal(t, LevelDebug, db.Level())
+	// and are listed with it, although there is no override for them
+	require.Equal(t, LevelDebug, state.Loggers["db"])
+
+	code, state = request(http.MethodPost, `{"logger":"db","level":"error","ttl":"20ms"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]Level{"db": LevelError}, state.Overrides)
+	require.Equal(t, LevelError, state.Loggers["db"])
+	require.Equal(t, LevelError, db.Level())
+
+	require.Eventually(t, func() bool {
+		return len(LevelOverrides()) == 0
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, LevelDebug, db.Level())
+
+	code, _ = request(http.MethodPut, `{"level":"warn","ttl":"20ms"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, LevelWarn, db.Level())
+	require.Eventually(t, func() bool {
+		return db.Level() == LevelDebug
+	}, time.Second, 5*time.Millisecond)
+
+	// extending a ttl replaces the revert, so the first timer can not restore
+	// the level early even if it fired already
+	request(http.MethodPut, `{"logger":"db","level":"error","ttl":"1h"}`)
+	first := h.reverts["db"]
+	request(http.MethodPut, `{"logger":"db","level":"warn","ttl":"1h"}`)
+	require.NotSame(t, first, h.reverts["db"])
+	request(http.MethodPut, `{"logger":"db","level":"debug"}`)
+	require.Empty(t, h.reverts)
+	RemoveLevelOverride("db")
+
+	code, _ = request(http.MethodPut, `{"level":"verbose"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPut, `{"level":"debug","ttl":"soon"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPut, `{"level":"debug","logger":"`+strings.Repeat("x", maxLevelChangeSize)+`"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodDelete, "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+}
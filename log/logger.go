@@ -1,12 +1,14 @@
 package log
 
 import (
+	"context"
 	"fmt"
-	_log "log"
-	"path/filepath"
-	"runtime"
-	"sort"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	color "gopkg.in/gookit/color.v1"
 )
@@ -33,9 +35,24 @@ type Context interface {
 	Map() Fields
 }
 
+// contextImpl is a node in a chain of fields. The fields of the whole chain
+// are flattened once on first use and cached, as the chain is immutable. The
+// cache is only allocated then, so that deriving loggers whose entries are
+// filtered stays cheap.
 type contextImpl struct {
 	parent Context
 	m      Fields
+
+	cache atomic.Pointer[contextCache]
+}
+
+// contextCache holds what is computed from the fields of a contextImpl
+type contextCache struct {
+	flat Fields
+
+	// slogHandlers caches the handlers of SlogSinks with the fields added,
+	// see SlogSink.Handle
+	slogHandlers sync.Map
 }
 
 var _ Context = (*contextImpl)(nil)
@@ -63,24 +80,46 @@ func (ci *contextImpl) WithValues(f Fields) Context {
 	return childCi
 }
 
+// flatten returns the cached fields of the whole chain, which must not be
+// modified
+func (ci *contextImpl) flatten() Fields {
+	return ci.cached().flat
+}
+
+// cached returns the cache of ci, flattening the fields on first use. Should
+// two goroutines race, both flatten but only the first cache is kept.
+func (ci *contextImpl) cached() *contextCache {
+	if c := ci.cache.Load(); c != nil {
+		return c
+	}
+	var parent Fields
+	if p, ok := ci.parent.(*contextImpl); ok {
+		parent = p.flatten()
+	} else if ci.parent != nil {
+		parent = ci.parent.Map()
+	}
+	flat := make(Fields, len(parent)+len(ci.m))
+	for k, v := range parent {
+		flat[k] = v
+	}
+	for k, v := range ci.m {
+		flat[k] = v
+	}
+	c := &contextCache{flat: flat}
+	if ci.cache.CompareAndSwap(nil, c) {
+		return c
+	}
+	return ci.cache.Load()
+}
+
+// Map returns a copy of the fields of the whole chain
 func (ci *contextImpl) Map() Fields {
-	if ci.parent != nil {
-		pm := ci.parent.Map()
-		nm := make(Fields, len(ci.m))
-		for k, v := range pm {
-			nm[k] = v
-		}
-		for k, v := range ci.m {
-			nm[k] = v
-		}
-		return nm
-	} else {
-		nm := make(Fields, len(ci.m))
-		for k, v := range ci.m {
-			nm[k] = v
-		}
-		return nm
+	flat := ci.flatten()
+	nm := make(Fields, len(flat))
+	for k, v := range flat {
+		nm[k] = v
 	}
+	return nm
 }
 
 type Level int32
@@ -91,159 +130,371 @@ const (
 	LevelWarn
 	LevelError
 	LevelPanic
+	LevelFatal
 )
 
 type Logger struct {
-	callDepth int
 	level     Level
-	logger    *_log.Logger
+	named     *namedLevel
+	handler   *sharedHandler
+	sampler   *Sampler
+	redaction *RedactionPolicy
+	ring      *RingBuffer
+	formatter *sharedFormatter
 	fields    Context
+
+	// levelParent is the logger a named logger takes its level from, unless
+	// a level override matches or its own level was set (ownLevel != 0)
+	levelParent *Logger
+	ownLevel    int32
+}
+
+// sharedFormatter holds the formatter override of a logger, which is shared
+// with all loggers derived from it
+type sharedFormatter struct {
+	mu        sync.RWMutex
+	formatter Formatter
+}
+
+func (sf *sharedFormatter) get() Formatter {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.formatter
+}
+
+func (sf *sharedFormatter) set(formatter Formatter) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	sf.formatter = formatter
+}
+
+// sharedHandler holds the handler of a logger, which is shared with all
+// loggers derived from it. It is replaced atomically, as it is read on every
+// log call.
+type sharedHandler struct {
+	// v holds a handlerBox, as atomic.Value requires a consistent type
+	v atomic.Value
+}
+
+type handlerBox struct {
+	Handler
+}
+
+func newSharedHandler(handler Handler) *sharedHandler {
+	sh := new(sharedHandler)
+	sh.set(handler)
+	return sh
+}
+
+func (sh *sharedHandler) get() Handler {
+	return sh.v.Load().(handlerBox).Handler
 }
 
-func New(level Level) *Logger {
+func (sh *sharedHandler) set(handler Handler) {
+	sh.v.Store(handlerBox{handler})
+}
+
+// New creates a logger for entries of at least the given level.
+// Unless configured otherwise by opts, entries are written to stderr using the
+// TextFormatter and fields are redacted using the DefaultRedactionPolicy.
+func New(level Level, opts ...Option) *Logger {
 	l := new(Logger)
-	l.callDepth = 3
 	l.level = level
-	l.logger = _log.Default()
+	l.handler = newSharedHandler(NewSink(os.Stderr, LevelDebug, &TextFormatter{}))
+	l.redaction = DefaultRedactionPolicy
+	l.formatter = new(sharedFormatter)
 	l.fields = emptyContextImpl()
+	for _, opt := range opts {
+		opt(l)
+	}
 	return l
 }
 
-func (l *Logger) getCaller() string {
-	if _, path, line, ok := runtime.Caller(l.callDepth); ok {
-		_log.Println(path)
-		_, file := filepath.Split(path)
-		return colorCaller.Sprint(file) + ":" + colorLine.Sprint(line)
+// Handler returns the handler entries are passed to. It is shared with all
+// loggers derived using WithField(s) or Named.
+func (l *Logger) Handler() Handler {
+	return l.handler.get()
+}
+
+// SetHandler replaces the handler of the logger and all loggers derived from
+// it, whether derived before or after
+func (l *Logger) SetHandler(handler Handler) {
+	l.handler.set(handler)
+}
+
+// Flush waits for buffered entries to be written if the logger's handler
+// buffers entries (see AsyncHandler)
+func (l *Logger) Flush(ctx context.Context) error {
+	if f, ok := l.handler.get().(Flusher); ok {
+		return f.Flush(ctx)
 	}
-	return "<???>"
+	return nil
 }
 
-func getSeverity(level Level) string {
+// Close flushes and closes the logger's handler if it is an io.Closer. As
+// handlers are shared, this affects all loggers derived using WithField(s).
+func (l *Logger) Close() error {
+	if c, ok := l.handler.get().(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// SetFormatter overrides the formatter of all sinks of the logger (see Sink)
+// for the entries of this logger and all loggers derived from it, whether
+// derived before or after. Other loggers sharing the same sinks are not
+// affected, use Sink.SetFormatter to change the formatter for all of them.
+// Handlers formatting entries on their own, e.g. SyslogSink, ignore it.
+// nil removes the override.
+func (l *Logger) SetFormatter(formatter Formatter) {
+	l.formatter.set(formatter)
+}
+
+func getSeverity(level Level, colored bool) string {
 	severity := ""
 	switch level {
 	case LevelDebug:
-		severity = colorDebug.Render("DEBUG")
+		severity = paint(colorDebug, colored, "DEBUG")
 	case LevelInfo:
-		severity = colorInfo.Render("INFO")
+		severity = paint(colorInfo, colored, "INFO")
 	case LevelWarn:
-		severity = colorWarn.Render("WARN")
+		severity = paint(colorWarn, colored, "WARN")
 	case LevelError:
-		severity = colorError.Render("ERROR")
+		severity = paint(colorError, colored, "ERROR")
 	case LevelPanic:
-		severity = colorError.Render("FATAL")
+		severity = paint(colorError, colored, "PANIC")
+	case LevelFatal:
+		severity = paint(colorError, colored, "FATAL")
 	}
 	return "[" + severity + "]"
 }
 
-func (l *Logger) WithField(key string, value interface{}) *Logger {
+// Level returns the minimum level of entries logged. For named loggers a
+// matching level override takes precedence, followed by the level set using
+// SetLevel and finally the current level of the logger it was derived from.
+func (l *Logger) Level() Level {
+	if l.named != nil {
+		if level, ok := l.named.get(); ok {
+			return level
+		}
+	}
+	if l.levelParent != nil && atomic.LoadInt32(&l.ownLevel) == 0 {
+		return l.levelParent.Level()
+	}
+	return Level(atomic.LoadInt32((*int32)(&l.level)))
+}
+
+// SetLevel changes the level of this logger only. Loggers derived before
+// using WithField(s) keep their level, named loggers without a level of their
+// own follow it.
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32((*int32)(&l.level), int32(level))
+	atomic.StoreInt32(&l.ownLevel, 1)
+}
+
+// Enabled reports whether entries of the given level are logged
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// accepts reports whether entries of the given level are logged or at least
+// recorded in the ring buffer
+func (l *Logger) accepts(level Level) bool {
+	return l.records(level) || l.Enabled(level)
+}
+
+// records reports whether entries of the given level are recorded in the ring
+// buffer
+func (l *Logger) records(level Level) bool {
+	return l.ring != nil && l.ring.Enabled(level)
+}
+
+// derive returns a copy of the logger using the given fields
+func (l *Logger) derive(fields Context) *Logger {
 	newLogger := new(Logger)
-	newLogger.callDepth = l.callDepth
-	newLogger.level = l.level
-	newLogger.logger = l.logger
-	newLogger.fields = l.fields.WithValue(key, value)
+	newLogger.level = Level(atomic.LoadInt32((*int32)(&l.level)))
+	newLogger.levelParent = l.levelParent
+	newLogger.ownLevel = atomic.LoadInt32(&l.ownLevel)
+	newLogger.named = l.named
+	newLogger.handler = l.handler
+	newLogger.sampler = l.sampler
+	newLogger.redaction = l.redaction
+	newLogger.ring = l.ring
+	newLogger.formatter = l.formatter
+	newLogger.fields = fields
 	return newLogger
 }
 
+func (l *Logger) WithField(key string, value interface{}) *Logger {
+	return l.derive(l.fields.WithValue(key, value))
+}
+
+// WithFields returns a derived logger adding the given fields to all entries.
+// fields is copied, later changes to it do not affect the logger.
 func (l *Logger) WithFields(fields Fields) *Logger {
-	newLogger := new(Logger)
-	newLogger.callDepth = l.callDepth
-	newLogger.level = l.level
-	newLogger.logger = l.logger
-	newLogger.fields = l.fields.WithValues(fields)
-	return newLogger
+	copied := make(Fields, len(fields))
+	for k, v := range fields {
+		copied[k] = v
+	}
+	return l.derive(l.fields.WithValues(copied))
+}
+
+// log passes an entry to the handler
+func (l *Logger) log(level Level, msgs ...interface{}) *Entry {
+	e := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Name:    l.Name(),
+		Message: strings.TrimSuffix(fmt.Sprintln(msgs...), "\n"),
+	}
+	if l.needsCaller(level) {
+		e.Caller = findCaller(nil)
+	}
+	l.emit(e, nil)
+	return e
+}
+
+// emit adds the logger's fields and the given ones to e and dispatches it,
+// unless it is disabled for its level or sampled out. In that case it is only
+// recorded in the ring buffer, if any.
+func (l *Logger) emit(e *Entry, fields []Field) {
+	handle := l.Enabled(e.Level) && l.handler.get().Enabled(e.Level) &&
+		(l.sampler == nil || l.sampler.allow(l, e))
+	if !handle && !l.records(e.Level) {
+		return
+	}
+	l.addFields(e, fields)
+	if handle {
+		l.dispatch(e)
+	} else {
+		l.record(e)
+	}
+}
+
+// addFields sets the fields of e to those of the logger and the given ones
+func (l *Logger) addFields(e *Entry, fields []Field) {
+	e.Fields = l.fields.Map()
+	for _, f := range fields {
+		e.Fields[f.Key] = f.Value()
+	}
+	e.context = l.fields
+	if len(fields) > 0 {
+		// copied, so that the fields of filtered entries do not escape
+		e.fields = append([]Field(nil), fields...)
+	}
 }
 
-func (l *Logger) structure(level Level, msgs ...interface{}) []interface{} {
-	//timestamp := "[" + time.Now().Format(time.RFC3339) + "]"
+// dispatch records a complete entry and passes it to the handler and the
+// registered hooks
+func (l *Logger) dispatch(e *Entry) {
+	l.record(e)
+	e.formatter = l.formatter.get()
+	if err := l.handler.get().Handle(e); err != nil {
+		fmt.Fprintf(os.Stderr, "log: unable to handle entry: %v: %s\n", err, e.Message)
+	}
+	runHooks(e)
+}
 
-	fieldMap := l.fields.Map()
-	_fields := make([]string, 0, len(fieldMap))
-	for k, v := range fieldMap {
-		_fields = append(_fields, "\t"+colorFieldKey.Render(k)+"="+colorFieldValue.Render(v))
+// record redacts the fields of a complete entry and adds it to the ring
+// buffer, if any
+func (l *Logger) record(e *Entry) {
+	if l.redaction != nil {
+		e.Fields = l.redaction.Apply(e.Fields)
 	}
-	sort.Strings(_fields)
-	//fields := strings.Join(_fields, ", ")
-	var fields string
-	if len(_fields) > 0 {
-		fields = "\n" + strings.Join(_fields, "\n")
+	e.redaction = l.redaction
+	if l.records(e.Level) {
+		l.ring.Handle(e)
 	}
+}
 
-	ctx := make([]interface{}, 3+len(msgs))
-	//ctx[0] = timestamp
-	ctx[0] = getSeverity(level)
-	ctx[1] = l.getCaller() + ":"
-	for i, v := range msgs {
-		ctx[2+i] = v
+// crash dumps the ring buffer, if any, and panics with the message of e
+func (l *Logger) crash(e *Entry) {
+	if l.ring != nil {
+		l.ring.Dump()
 	}
-	ctx[len(ctx)-1] = fields
-	return ctx
+	panic(e.Message)
 }
 
 func (l *Logger) Debug(msgs ...interface{}) {
-	if l.level <= LevelDebug {
-		l.logger.Println(l.structure(LevelDebug, msgs...)...)
+	if l.accepts(LevelDebug) {
+		l.log(LevelDebug, msgs...)
 	}
 }
 
 func (l *Logger) Debugf(f string, msgs ...interface{}) {
-	if l.level <= LevelDebug {
-		l.logger.Println(l.structure(LevelDebug, fmt.Sprintf(f, msgs...))...)
+	if l.accepts(LevelDebug) {
+		l.log(LevelDebug, fmt.Sprintf(f, msgs...))
 	}
 }
 
 func (l *Logger) Info(msgs ...interface{}) {
-	if l.level <= LevelInfo {
-		l.logger.Println(l.structure(LevelInfo, msgs...)...)
+	if l.accepts(LevelInfo) {
+		l.log(LevelInfo, msgs...)
 	}
 }
 
 func (l *Logger) Infof(f string, msgs ...interface{}) {
-	if l.level <= LevelInfo {
-		l.logger.Println(l.structure(LevelInfo, fmt.Sprintf(f, msgs...))...)
+	if l.accepts(LevelInfo) {
+		l.log(LevelInfo, fmt.Sprintf(f, msgs...))
 	}
 }
 
 func (l *Logger) Warn(msgs ...interface{}) {
-	if l.level <= LevelWarn {
-		l.logger.Println(l.structure(LevelWarn, msgs...)...)
+	if l.accepts(LevelWarn) {
+		l.log(LevelWarn, msgs...)
 	}
 }
 
 func (l *Logger) Warnf(f string, msgs ...interface{}) {
-	if l.level <= LevelWarn {
-		l.logger.Println(l.structure(LevelWarn, fmt.Sprintf(f, msgs...))...)
+	if l.accepts(LevelWarn) {
+		l.log(LevelWarn, fmt.Sprintf(f, msgs...))
 	}
 }
 
 func (l *Logger) Error(msgs ...interface{}) {
-	if l.level <= LevelError {
-		l.logger.Println(l.structure(LevelError, msgs...)...)
+	if l.accepts(LevelError) {
+		l.log(LevelError, msgs...)
 	}
 }
 
 func (l *Logger) Errorf(f string, msgs ...interface{}) {
-	if l.level <= LevelError {
-		l.logger.Println(l.structure(LevelError, fmt.Sprintf(f, msgs...))...)
+	if l.accepts(LevelError) {
+		l.log(LevelError, fmt.Sprintf(f, msgs...))
 	}
 }
 
 func (l *Logger) Panic(msgs ...interface{}) {
-	if l.level <= LevelPanic {
-		l.logger.Panicln(l.structure(LevelPanic, msgs...)...)
+	if l.Enabled(LevelPanic) {
+		l.crash(l.log(LevelPanic, msgs...))
 	}
 }
 
 func (l *Logger) Panicf(f string, msgs ...interface{}) {
-	if l.level <= LevelPanic {
-		l.logger.Panicln(l.structure(LevelPanic, fmt.Sprintf(f, msgs...))...)
+	if l.Enabled(LevelPanic) {
+		l.crash(l.log(LevelPanic, fmt.Sprintf(f, msgs...)))
+	}
+}
+
+// Fatal logs the message, runs the exit hooks, flushes the handlers and exits
+// the program, see AddExitHook and ExitCode
+func (l *Logger) Fatal(msgs ...interface{}) {
+	if l.Enabled(LevelFatal) {
+		l.log(LevelFatal, msgs...)
 	}
+	l.exit()
+}
+
+func (l *Logger) Fatalf(f string, msgs ...interface{}) {
+	if l.Enabled(LevelFatal) {
+		l.log(LevelFatal, fmt.Sprintf(f, msgs...))
+	}
+	l.exit()
 }
 
 var defaultLogger *Logger
 
 func init() {
 	defaultLogger = New(LevelInfo)
-	defaultLogger.callDepth = 4
 }
 
 func WithField(key string, value interface{}) *Logger {
@@ -294,6 +545,26 @@ func Panicf(f string, msgs ...interface{}) {
 	defaultLogger.Panicf(f, msgs...)
 }
 
+func Fatal(msgs ...interface{}) {
+	defaultLogger.Fatal(msgs...)
+}
+
+func Fatalf(f string, msgs ...interface{}) {
+	defaultLogger.Fatalf(f, msgs...)
+}
+
 func SetLevel(level Level) {
-	defaultLogger.level = level
+	defaultLogger.SetLevel(level)
+}
+
+// SetFormatter sets the formatter of the default logger and all loggers
+// derived from it, see Logger.SetFormatter
+func SetFormatter(formatter Formatter) {
+	defaultLogger.SetFormatter(formatter)
+}
+
+// SetHandler replaces the handler of the default logger and all loggers
+// derived from it, see Logger.SetHandler
+func SetHandler(handler Handler) {
+	defaultLogger.SetHandler(handler)
 }
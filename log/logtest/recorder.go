@@ -0,0 +1,153 @@
+// Package logtest provides a log.Handler recording entries in memory, so
+// tests can assert on what has been logged.
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ms-xy/go-common/log"
+)
+
+// Recorder is a log.Handler keeping every entry it receives
+type Recorder struct {
+	t         testing.TB
+	formatter log.Formatter
+
+	mu      sync.Mutex
+	entries []log.Entry
+}
+
+var _ log.Handler = (*Recorder)(nil)
+
+// Option configures a Recorder
+type Option func(*Recorder)
+
+// WithTestOutput additionally writes every entry to t.Log, so it is shown
+// for failed tests (or when running with -v). If formatter is nil the
+// LogfmtFormatter is used.
+func WithTestOutput(formatter log.Formatter) Option {
+	return func(r *Recorder) {
+		if formatter == nil {
+			formatter = &log.LogfmtFormatter{}
+		}
+		r.formatter = formatter
+	}
+}
+
+// NewRecorder creates a recorder reporting failed assertions to t
+func NewRecorder(t testing.TB, opts ...Option) *Recorder {
+	r := &Recorder{t: t}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Logger returns a logger of the given level writing to the recorder
+func (r *Recorder) Logger(level log.Level) *log.Logger {
+	return log.New(level, log.WithHandler(r))
+}
+
+// Enabled always returns true, the recorder keeps all entries passed on by
+// the logger
+func (r *Recorder) Enabled(level log.Level) bool {
+	return true
+}
+
+func (r *Recorder) Handle(e *log.Entry) error {
+	entry := *e
+	entry.Fields = make(log.Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		entry.Fields[k] = v
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+
+	if r.formatter != nil {
+		if buf, err := r.formatter.Format(&entry); err == nil {
+			r.t.Log(strings.TrimSuffix(string(buf), "\n"))
+		}
+	}
+	return nil
+}
+
+// Entries returns a copy of all entries recorded so far
+func (r *Recorder) Entries() []log.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]log.Entry(nil), r.entries...)
+}
+
+// Reset discards all recorded entries
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Find returns the entries of the given level whose message contains
+// msgSubstring and whose fields contain all of fields (which may be nil)
+func (r *Recorder) Find(level log.Level, msgSubstring string, fields log.Fields) []log.Entry {
+	found := make([]log.Entry, 0)
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Message, msgSubstring) && containsFields(e.Fields, fields) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func containsFields(actual, expected log.Fields) bool {
+	for k, v := range expected {
+		av, exists := actual[k]
+		if !exists {
+			return false
+		}
+		if !reflect.DeepEqual(av, v) && fmt.Sprint(av) != fmt.Sprint(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertLogged fails the test unless an entry matching the arguments (see
+// Find) has been recorded
+func (r *Recorder) AssertLogged(level log.Level, msgSubstring string, fields log.Fields) bool {
+	r.t.Helper()
+	if len(r.Find(level, msgSubstring, fields)) == 0 {
+		r.t.Errorf("expected a %s entry containing %q with fields %v, recorded:\n%s",
+			level, msgSubstring, fields, r.dump())
+		return false
+	}
+	return true
+}
+
+// AssertNotLogged fails the test if an entry matching the arguments (see
+// Find) has been recorded
+func (r *Recorder) AssertNotLogged(level log.Level, msgSubstring string, fields log.Fields) bool {
+	r.t.Helper()
+	if found := r.Find(level, msgSubstring, fields); len(found) > 0 {
+		r.t.Errorf("expected no %s entry containing %q with fields %v, found %d:\n%s",
+			level, msgSubstring, fields, len(found), r.dump())
+		return false
+	}
+	return true
+}
+
+func (r *Recorder) dump() string {
+	entries := r.Entries()
+	if len(entries) == 0 {
+		return "\t<none>"
+	}
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("\t[%s] %s %v", e.Level, e.Message, e.Fields)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+package logtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ms-xy/go-common/log"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB records errors instead of failing the test
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Failed() bool {
+	return len(f.errors) > 0
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(t, WithTestOutput(nil))
+	l := r.Logger(log.LevelInfo)
+
+	l.Debug("filtered by the logger")
+	l.WithFields(log.Fields{"user": "jane", "attempt": 2}).Warn("login failed")
+
+	entries := r.Entries()
+	require.Len(t, entries, 1)
+	require.Equal(t, "recorder_test.go", entries[0].Caller.File[len(entries[0].Caller.File)-len("recorder_test.go"):])
+
+	r.AssertLogged(log.LevelWarn, "login", log.Fields{"user": "jane", "attempt": 2})
+	r.AssertNotLogged(log.LevelDebug, "", nil)
+
+	// failed assertions are reported to the test
+	fake := &fakeTB{TB: t}
+	failing := NewRecorder(fake)
+	require.False(t, failing.AssertLogged(log.LevelInfo, "anything", nil))
+	require.True(t, fake.Failed())
+	require.Contains(t, fake.errors[0], `expected a info entry containing "anything"`)
+
+	r.Reset()
+	require.Len(t, r.Entries(), 0)
+}
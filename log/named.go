@@ -0,0 +1,291 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// noOverride marks a named logger without a matching level override
+const noOverride = -1
+
+// namedLevel is shared by all loggers of the same name and holds the level of
+// the most specific matching override
+type namedLevel struct {
+	name     string
+	override int32
+}
+
+func (n *namedLevel) get() (Level, bool) {
+	if level := atomic.LoadInt32(&n.override); level != noOverride {
+		return Level(level), true
+	}
+	return 0, false
+}
+
+// levelRegistry keeps track of the level overrides and all named loggers. Names
+// are never removed, see Logger.Named.
+type levelRegistry struct {
+	mu        sync.Mutex
+	overrides map[string]Level
+	names     map[string]*namedLevel
+}
+
+var registry = &levelRegistry{
+	overrides: make(map[string]Level),
+	names:     make(map[string]*namedLevel),
+}
+
+func (r *levelRegistry) get(name string) *namedLevel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n, exists := r.names[name]; exists {
+		return n
+	}
+	n := &namedLevel{name: name, override: noOverride}
+	r.apply(n)
+	r.names[name] = n
+	return n
+}
+
+// apply sets the override of n to the level of the most specific matching
+// pattern. Must be called with r.mu held.
+func (r *levelRegistry) apply(n *namedLevel) {
+	best := -1
+	level := int32(noOverride)
+	for pattern, l := range r.overrides {
+		if specificity := matchPattern(pattern, n.name); specificity > best {
+			best = specificity
+			level = int32(l)
+		}
+	}
+	atomic.StoreInt32(&n.override, level)
+}
+
+// set replaces all overrides and updates all named loggers
+func (r *levelRegistry) set(overrides map[string]Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.overrides = overrides
+	for _, n := range r.names {
+		r.apply(n)
+	}
+}
+
+// matchPattern returns how specific pattern matches name or -1 if it does not
+// match at all. Patterns are
+//
+//   - "*" matches all loggers
+//   - "db" matches db and all loggers below, e.g. db.transactions
+//   - "db.*" matches all loggers below db, but not db itself
+//
+// Longer patterns are more specific, an exact name beats a wildcard.
+func matchPattern(pattern, name string) int {
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasSuffix(pattern, ".*"):
+		if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+			return 2 * (len(pattern) - 1)
+		}
+	case pattern == name:
+		return 2*len(pattern) + 1
+	case strings.HasPrefix(name, pattern+"."):
+		return 2 * len(pattern)
+	}
+	return -1
+}
+
+// Named returns a logger derived from the default logger with the given name.
+// Its level is controlled by the level overrides (see SetLevelOverrides),
+// falling back to the current level of the default logger.
+func Named(name string) *Logger {
+	return defaultLogger.Named(name)
+}
+
+// Named returns a derived logger with the given name. If l is named already,
+// the name is appended, separated by a dot. Unless a level override matches or
+// its level is set explicitly, the named logger follows the level of l.
+//
+// Every name is registered for the lifetime of the process, so that level
+// overrides can be applied to it. Names must therefore come from a fixed set,
+// e.g. components or packages, never from requests, tenants or other
+// unbounded input. Use WithField for those.
+func (l *Logger) Named(name string) *Logger {
+	if l.named != nil {
+		name = l.named.name + "." + name
+	}
+	newLogger := l.derive(l.fields)
+	newLogger.named = registry.get(name)
+	newLogger.levelParent = l
+	newLogger.ownLevel = 0
+	return newLogger
+}
+
+// Name returns the name of the logger, see Named
+func (l *Logger) Name() string {
+	if l.named == nil {
+		return ""
+	}
+	return l.named.name
+}
+
+// SetLevelOverride sets the level of all named loggers matching pattern, see
+// SetLevelOverrides
+func SetLevelOverride(pattern string, level Level) {
+	overrides := LevelOverrides()
+	overrides[pattern] = level
+	registry.set(overrides)
+}
+
+// RemoveLevelOverride removes the override set for pattern
+func RemoveLevelOverride(pattern string) {
+	overrides := LevelOverrides()
+	delete(overrides, pattern)
+	registry.set(overrides)
+}
+
+// LevelOverrides returns a copy of the current level overrides
+func LevelOverrides() map[string]Level {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	overrides := make(map[string]Level, len(registry.overrides))
+	for pattern, level := range registry.overrides {
+		overrides[pattern] = level
+	}
+	return overrides
+}
+
+// NamedLevels returns the names of all named loggers created so far and their
+// effective override level. Loggers without a matching override are omitted.
+func NamedLevels() map[string]Level {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	levels := make(map[string]Level, len(registry.names))
+	for name, n := range registry.names {
+		if level, ok := n.get(); ok {
+			levels[name] = level
+		}
+	}
+	return levels
+}
+
+// levels returns the effective level of all named loggers, fallback for those
+// without a matching override
+func (r *levelRegistry) levels(fallback Level) map[string]Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	levels := make(map[string]Level, len(r.names))
+	for name, n := range r.names {
+		if level, ok := n.get(); ok {
+			levels[name] = level
+		} else {
+			levels[name] = fallback
+		}
+	}
+	return levels
+}
+
+// SetLevelOverrides replaces all level overrides with those given in spec,
+// a comma or whitespace separated list of pattern=level pairs, e.g.
+//
+//	db.*=debug,http=warn
+//
+// The change applies to existing named loggers immediately. An empty spec
+// removes all overrides.
+func SetLevelOverrides(spec string) error {
+	overrides, err := ParseLevelOverrides(spec)
+	if err != nil {
+		return err
+	}
+	registry.set(overrides)
+	return nil
+}
+
+// ParseLevelOverrides parses a spec as accepted by SetLevelOverrides
+func ParseLevelOverrides(spec string) (map[string]Level, error) {
+	overrides := make(map[string]Level)
+	for _, pair := range strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		pattern, levelName, ok := strings.Cut(pair, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid level override '%s', expected pattern=level", pair)
+		}
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+		overrides[pattern] = level
+	}
+	return overrides, nil
+}
+
+// ParseLevel parses the (case insensitive) name of a level as returned by
+// Level.String
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "panic":
+		return LevelPanic, nil
+	case "fatal":
+		return LevelFatal, nil
+	}
+	return 0, fmt.Errorf("unknown log level '%s'", name)
+}
+
+// ConfigGetter is the subset of configuration.ConfigurationLoader needed by
+// LoadLevelOverrides
+type ConfigGetter interface {
+	Get(key string) interface{}
+}
+
+// LoadLevelOverrides replaces the level overrides with those found at key.
+// The value may either be a spec string as accepted by SetLevelOverrides, a
+// list of pattern=level strings or a map of patterns to level names (nested
+// maps are joined with dots). A missing key removes all overrides.
+func LoadLevelOverrides(config ConfigGetter, key string) error {
+	var spec string
+	switch value := config.Get(key).(type) {
+	case nil:
+	case string:
+		spec = value
+	case []interface{}:
+		pairs := make([]string, len(value))
+		for i, v := range value {
+			pairs[i] = fmt.Sprint(v)
+		}
+		spec = strings.Join(pairs, ",")
+	case map[string]interface{}:
+		pairs := flattenLevelMap(value, "", make([]string, 0, len(value)))
+		sort.Strings(pairs)
+		spec = strings.Join(pairs, ",")
+	default:
+		return errors.New("unsupported type " + fmt.Sprintf("%T", value) + " for level overrides in key " + key)
+	}
+	return SetLevelOverrides(spec)
+}
+
+func flattenLevelMap(m map[string]interface{}, prefix string, pairs []string) []string {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			pairs = flattenLevelMap(nested, k, pairs)
+		} else {
+			pairs = append(pairs, k+"="+fmt.Sprint(v))
+		}
+	}
+	return pairs
+}
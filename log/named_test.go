@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mapConfig map[string]interface{}
+
+func (c mapConfig) Get(key string) interface{} {
+	return c[key]
+}
+
+func TestNamedLevelOverrides(t *testing.T) {
+	defer SetLevelOverrides("")
+
+	buf := new(bytes.Buffer)
+	root := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+	db := root.Named("db")
+	tx := db.Named("transactions")
+	http := root.Named("http")
+	require.Equal(t, "db.transactions", tx.Name())
+
+	require.Nil(t, SetLevelOverrides("db.*=debug, http=error"))
+	require.Equal(t, LevelInfo, db.Level())
+	require.Equal(t, LevelDebug, tx.Level())
+	require.Equal(t, LevelError, http.Level())
+
+	tx.Debug("begin")
+	db.Debug("filtered")
+	http.Warn("filtered")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	require.Contains(t, lines[0], "level=debug logger=db.transactions")
+
+	require.Nil(t, LoadLevelOverrides(mapConfig{"logging": map[string]interface{}{
+		"db":              "warn",
+		"db.transactions": "error",
+	}}, "logging"))
+	require.Equal(t, LevelWarn, db.Level())
+	require.Equal(t, LevelError, tx.Level())
+	require.Equal(t, LevelInfo, http.Level())
+
+	require.NotNil(t, SetLevelOverrides("db=verbose"))
+	require.NotNil(t, SetLevelOverrides("db"))
+
+	// without an override named loggers follow the level of their parent
+	require.Nil(t, SetLevelOverrides(""))
+	root.SetLevel(LevelError)
+	require.Equal(t, LevelError, tx.Level())
+	require.Equal(t, LevelError, db.WithField("a", 1).Level())
+	db.SetLevel(LevelDebug)
+	require.Equal(t, LevelDebug, tx.Level())
+	require.Equal(t, LevelError, http.Level())
+}
+
+func TestMatchPattern(t *testing.T) {
+	require.Equal(t, -1, matchPattern("db", "dbx"))
+	require.Equal(t, -1, matchPattern("db.*", "db"))
+	require.True(t, matchPattern("db.*", "db.tx") > matchPattern("db", "db.tx"))
+	require.True(t, matchPattern("db.tx", "db.tx") > matchPattern("db.*", "db.tx"))
+	require.True(t, matchPattern("db", "db.tx") > matchPattern("*", "db.tx"))
+}
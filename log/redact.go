@@ -0,0 +1,268 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces redacted values and secrets within strings
+const RedactedValue = "<redacted>"
+
+// maxRedactionDepth limits how deep nested values are inspected
+const maxRedactionDepth = 8
+
+// DefaultSecretKeyPatterns lists the (lower case) substrings which mark a
+// field as secret. The configuration package starts out with a copy of them
+// for its keys.
+var DefaultSecretKeyPatterns = []string{
+	"password", "passwd", "secret", "token", "apikey", "api_key", "private",
+	"credential", "authorization", "cookie",
+}
+
+// CardNumberPattern matches credit card numbers of 13 to 19 digits, optionally
+// grouped by spaces or dashes. Its matches are only redacted if they pass the
+// Luhn check, so other long numbers (e.g. IDs) are left alone.
+var CardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+// DefaultSecretValuePatterns match secrets within string values: credit card
+// numbers and bearer tokens
+var DefaultSecretValuePatterns = []*regexp.Regexp{
+	CardNumberPattern,
+	regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`),
+}
+
+// DefaultRedactionPolicy is used by all loggers unless configured otherwise
+// using WithRedaction
+var DefaultRedactionPolicy = &RedactionPolicy{
+	KeyPatterns:   DefaultSecretKeyPatterns,
+	ValuePatterns: DefaultSecretValuePatterns,
+}
+
+// RedactionPolicy decides which field values are replaced by RedactedValue
+// before entries are passed to the handler. Maps, slices and structs are
+// inspected recursively, keys (or struct fields, using their json name if
+// tagged) matching a key pattern are redacted at any depth. Values wrapped in
+// Redacted are always redacted.
+type RedactionPolicy struct {
+	// KeyPatterns are lower case substrings marking a key as secret
+	KeyPatterns []string
+	// ValuePatterns match secrets within strings and error messages, only the
+	// matching part is replaced
+	ValuePatterns []*regexp.Regexp
+}
+
+// Apply redacts fields in place and returns them
+func (p *RedactionPolicy) Apply(fields Fields) Fields {
+	for k, v := range fields {
+		if p.isSecretKey(k) {
+			fields[k] = RedactedValue
+		} else if redacted, changed := p.redact(v, 0); changed {
+			fields[k] = redacted
+		}
+	}
+	return fields
+}
+
+func (p *RedactionPolicy) isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range p.KeyPatterns {
+		if strings.Contains(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RedactionPolicy) redactString(s string) (string, bool) {
+	changed := false
+	for _, pattern := range p.ValuePatterns {
+		if !pattern.MatchString(s) {
+			continue
+		}
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if pattern == CardNumberPattern && !luhnValid(match) {
+				return match
+			}
+			changed = true
+			return RedactedValue
+		})
+	}
+	return s, changed
+}
+
+// luhnValid reports whether the digits of number pass the Luhn check
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// redact returns v with all secrets replaced and whether anything has been
+// replaced at all. Changed maps and structs are returned as
+// map[string]interface{}, changed slices as []interface{}.
+func (p *RedactionPolicy) redact(v interface{}, depth int) (interface{}, bool) {
+	switch value := v.(type) {
+	case nil:
+		return v, false
+	case Redacted, *Redacted:
+		return RedactedValue, true
+	case string:
+		// v is returned as is if unchanged, to avoid converting value again
+		if s, changed := p.redactString(value); changed {
+			return s, true
+		}
+		return v, false
+	case error:
+		if s, changed := p.redactString(value.Error()); changed {
+			return s, true
+		}
+		return v, false
+	case fmt.Stringer, json.Marshaler:
+		// e.g. time.Time, these render themselves
+		return v, false
+	}
+	if depth >= maxRedactionDepth {
+		return v, false
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+			return p.redactStruct(v, rv.Elem(), depth)
+		}
+	case reflect.Struct:
+		return p.redactStruct(v, rv, depth)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return p.redactMap(v, rv, depth)
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			return p.redactSlice(v, rv, depth)
+		}
+	}
+	return v, false
+}
+
+func (p *RedactionPolicy) redactMap(v interface{}, rv reflect.Value, depth int) (interface{}, bool) {
+	m := make(map[string]interface{}, rv.Len())
+	changed := false
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		value := iter.Value().Interface()
+		if p.isSecretKey(k) {
+			m[k] = RedactedValue
+			changed = true
+		} else {
+			redacted, c := p.redact(value, depth+1)
+			m[k] = redacted
+			changed = changed || c
+		}
+	}
+	if !changed {
+		return v, false
+	}
+	return m, true
+}
+
+func (p *RedactionPolicy) redactSlice(v interface{}, rv reflect.Value, depth int) (interface{}, bool) {
+	s := make([]interface{}, rv.Len())
+	changed := false
+	for i := range s {
+		redacted, c := p.redact(rv.Index(i).Interface(), depth+1)
+		s[i] = redacted
+		changed = changed || c
+	}
+	if !changed {
+		return v, false
+	}
+	return s, true
+}
+
+func (p *RedactionPolicy) redactStruct(v interface{}, rv reflect.Value, depth int) (interface{}, bool) {
+	t := rv.Type()
+	m := make(map[string]interface{}, t.NumField())
+	changed := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		if p.isSecretKey(name) {
+			m[name] = RedactedValue
+			changed = true
+		} else {
+			redacted, c := p.redact(rv.Field(i).Interface(), depth+1)
+			m[name] = redacted
+			changed = changed || c
+		}
+	}
+	if !changed {
+		return v, false
+	}
+	return m, true
+}
+
+// WithRedaction sets the redaction policy of the logger and all loggers
+// derived from it, nil disables redaction (except for Redacted values)
+func WithRedaction(policy *RedactionPolicy) Option {
+	return func(l *Logger) {
+		l.redaction = policy
+	}
+}
+
+// -------------------------------------------------------------------------- //
+
+// Redacted wraps a value which must never be logged, it is rendered as
+// RedactedValue by all formatters and fmt
+type Redacted struct {
+	Value interface{}
+}
+
+// Redact wraps v in Redacted
+func Redact(v interface{}) Redacted {
+	return Redacted{Value: v}
+}
+
+func (r Redacted) String() string {
+	return RedactedValue
+}
+
+func (r Redacted) Format(f fmt.State, verb rune) {
+	io.WriteString(f, RedactedValue)
+}
+
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(RedactedValue)
+}
+
+func (r Redacted) LogValue() slog.Value {
+	return slog.StringValue(RedactedValue)
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type dbSettings struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	Options  map[string]string
+	internal string
+}
+
+func TestRedaction(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &JSONFormatter{})))
+
+	l.WithFields(Fields{
+		"user":     "jane",
+		"Password": "hunter2",
+		"pin":      Redact(1234),
+		"header":   "Authorization: Bearer abc.def-123",
+		"payment":  "card 4111 1111 1111 1111 declined",
+		"order":    "order 1234567890123456 shipped",
+		"db": dbSettings{
+			Host:     "localhost",
+			Password: "hunter2",
+			Options:  map[string]string{"sslmode": "disable", "api_key": "k"},
+		},
+		"hosts": []string{"a", "b"},
+	}).Info("connecting")
+
+	var logged map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &logged))
+	require.Equal(t, "jane", logged["user"])
+	require.Equal(t, RedactedValue, logged["Password"])
+	require.Equal(t, RedactedValue, logged["pin"])
+	require.Equal(t, "Authorization: <redacted>", logged["header"])
+	require.Equal(t, "card <redacted> declined", logged["payment"])
+	// not a valid card number
+	require.Equal(t, "order 1234567890123456 shipped", logged["order"])
+	require.Equal(t, map[string]interface{}{
+		"host":     "localhost",
+		"password": RedactedValue,
+		"Options":  map[string]interface{}{"sslmode": "disable", "api_key": RedactedValue},
+	}, logged["db"])
+	require.Equal(t, []interface{}{"a", "b"}, logged["hosts"])
+
+	// Redacted values are never rendered, even without a policy
+	buf.Reset()
+	l = New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})), WithRedaction(nil))
+	l.WithFields(Fields{"password": "hunter2", "pin": Redact(1234)}).Info("unredacted")
+	require.Contains(t, buf.String(), "password=hunter2 pin=<redacted>")
+	require.Equal(t, RedactedValue, fmt.Sprintf("%#v", Redact("x")))
+}
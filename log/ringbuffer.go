@@ -0,0 +1,172 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RingBuffer is a Handler keeping the most recent entries in memory. Used via
+// WithRingBuffer it records entries of all levels, including those below the
+// logger's level, so the lines leading up to a crash can be dumped by
+// Panic and Fatal or on demand, e.g. by serving the buffer over HTTP:
+//
+//	GET /debug/log?level=debug&format=json
+//
+// format is either logfmt (the default) or json.
+//
+// As every entry recorded has to be built, even those of disabled levels,
+// a ring buffer makes disabled log calls as expensive as logged ones. Use
+// SetLevel to restrict it to the levels worth keeping.
+type RingBuffer struct {
+	level   Level
+	mu      sync.Mutex
+	entries []*Entry
+	next    int
+	full    bool
+	dump    Handler
+}
+
+var _ Handler = (*RingBuffer)(nil)
+var _ http.Handler = (*RingBuffer)(nil)
+
+// NewRingBuffer creates a buffer keeping the last size entries. Dump writes
+// them to w (stderr if nil) using the TextFormatter.
+func NewRingBuffer(size int, w io.Writer) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	if w == nil {
+		w = os.Stderr
+	}
+	return &RingBuffer{
+		entries: make([]*Entry, size),
+		dump:    NewSink(w, LevelDebug, &TextFormatter{}),
+	}
+}
+
+// Enabled reports whether entries of the given level are kept, by default
+// entries of all levels are
+func (rb *RingBuffer) Enabled(level Level) bool {
+	return level >= Level(atomic.LoadInt32((*int32)(&rb.level)))
+}
+
+// SetLevel changes the minimum level of the entries kept by the buffer
+func (rb *RingBuffer) SetLevel(level Level) {
+	atomic.StoreInt32((*int32)(&rb.level), int32(level))
+}
+
+// Handle adds a copy of e to the buffer, replacing the oldest entry if it is
+// full
+func (rb *RingBuffer) Handle(e *Entry) error {
+	copied := *e
+	if e.Fields != nil {
+		copied.Fields = make(Fields, len(e.Fields))
+		for k, v := range e.Fields {
+			copied.Fields[k] = v
+		}
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.entries[rb.next] = &copied
+	rb.next = (rb.next + 1) % len(rb.entries)
+	if rb.next == 0 {
+		rb.full = true
+	}
+	return nil
+}
+
+// Entries returns the buffered entries, oldest first
+func (rb *RingBuffer) Entries() []*Entry {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if !rb.full {
+		return append([]*Entry(nil), rb.entries[:rb.next]...)
+	}
+	entries := make([]*Entry, 0, len(rb.entries))
+	entries = append(entries, rb.entries[rb.next:]...)
+	return append(entries, rb.entries[:rb.next]...)
+}
+
+// Dump writes all buffered entries to the writer given to NewRingBuffer
+func (rb *RingBuffer) Dump() {
+	entries := rb.Entries()
+	if err := rb.dump.Handle(&Entry{
+		Time:    time.Now(),
+		Level:   LevelInfo,
+		Message: fmt.Sprintf("dumping the last %d log entries", len(entries)),
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "log: unable to dump ring buffer: %v\n", err)
+		return
+	}
+	for _, e := range entries {
+		if err := rb.dump.Handle(e); err != nil {
+			fmt.Fprintf(os.Stderr, "log: unable to dump ring buffer: %v\n", err)
+			return
+		}
+	}
+}
+
+// WriteTo writes all buffered entries of at least level to w using formatter
+func (rb *RingBuffer) WriteTo(w io.Writer, level Level, formatter Formatter) error {
+	for _, e := range rb.Entries() {
+		if e.Level < level {
+			continue
+		}
+		buf, err := formatter.Format(e)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rb *RingBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	level := LevelDebug
+	if name := r.URL.Query().Get("level"); name != "" {
+		var err error
+		if level, err = ParseLevel(name); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var formatter Formatter
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "logfmt":
+		formatter = &LogfmtFormatter{}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	case "json":
+		formatter = &JSONFormatter{}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		http.Error(w, "invalid request: unknown format '"+format+"'", http.StatusBadRequest)
+		return
+	}
+	if err := rb.WriteTo(w, level, formatter); err != nil {
+		// the response has been started already, the client sees a
+		// truncated body
+		fmt.Fprintf(os.Stderr, "log: unable to serve ring buffer: %v\n", err)
+	}
+}
+
+// WithRingBuffer records the entries of the logger and the loggers derived
+// from it in rb, regardless of their level. Only the level of rb itself (see
+// RingBuffer.SetLevel) limits which entries are built and recorded.
+func WithRingBuffer(rb *RingBuffer) Option {
+	return func(l *Logger) {
+		l.ring = rb
+	}
+}
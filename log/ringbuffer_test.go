@@ -0,0 +1,73 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingBuffer(t *testing.T) {
+	out := new(bytes.Buffer)
+	dump := new(bytes.Buffer)
+	rb := NewRingBuffer(3, dump)
+	l := New(LevelInfo,
+		WithHandler(NewSink(out, LevelDebug, &LogfmtFormatter{})),
+		WithRingBuffer(rb))
+
+	l.Debug("one")
+	l.WithField("password", "hunter2").Debug("two")
+	l.DebugFields("three", Int("n", 3))
+	l.Info("four")
+	require.Equal(t, 1, strings.Count(out.String(), "\n"))
+
+	entries := rb.Entries()
+	require.Len(t, entries, 3)
+	require.Equal(t, "two", entries[0].Message)
+	require.Equal(t, RedactedValue, entries[0].Fields["password"])
+	require.Equal(t, "three", entries[1].Message)
+	require.Equal(t, "four", entries[2].Message)
+
+	require.PanicsWithValue(t, "crash", func() { l.Panic("crash") })
+	dumped := dump.String()
+	require.Contains(t, dumped, "dumping the last 3 log entries")
+	require.Contains(t, dumped, ": three")
+	require.Contains(t, dumped, "[PANIC]")
+
+	rec := httptest.NewRecorder()
+	rb.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?level=info&format=json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `"msg":"four"`)
+	require.Contains(t, lines[1], `"level":"panic"`)
+
+	rec = httptest.NewRecorder()
+	rb.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=xml", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRingBufferLevel(t *testing.T) {
+	rb := NewRingBuffer(3, io.Discard)
+	rb.SetLevel(LevelInfo)
+	l := New(LevelWarn, WithHandler(handlerFunc(func(e *Entry) error {
+		// handlers must not be able to change the recorded entry
+		e.Fields["n"] = "changed"
+		e.Message = "changed"
+		return nil
+	})), WithRingBuffer(rb))
+
+	l.Debug("one")
+	l.InfoFields("two", Int("n", 2))
+	l.WarnFields("three", Int("n", 3))
+
+	entries := rb.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "two", entries[0].Message)
+	require.Equal(t, "three", entries[1].Message)
+	require.EqualValues(t, 3, entries[1].Fields["n"])
+}
@@ -0,0 +1,345 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotateOptions configures a RotatingFile
+type RotateOptions struct {
+	// MaxSize rotates the file before a write would exceed this many bytes,
+	// 0 disables size based rotation
+	MaxSize int64
+	// Daily rotates the file at local midnight
+	Daily bool
+	// MaxBackups is the number of rotated files to keep, 0 keeps all
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+	// ReopenOnSIGHUP reopens the file whenever the process receives SIGHUP
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFile is an io.WriteCloser appending to a file which is rotated by
+// size and/or daily. Rotated files are renamed to <filename>.<timestamp>
+// (plus .gz if compressed), where the timestamp is the time of the rotation,
+// i.e. the end of the period covered by the file. Files rotated within the same
+// millisecond get a sequence number appended to the timestamp.
+//
+// If rotating fails, e.g. because the file can not be renamed, writing
+// continues on the current file and rotating is retried after a minute.
+//
+// It is safe for concurrent use, e.g. as the writer of a Sink shared by many
+// loggers. Fatal closes all RotatingFiles not closed yet.
+type RotatingFile struct {
+	mu             sync.Mutex
+	filename       string
+	opts           RotateOptions
+	file           *os.File
+	closed         bool
+	size           int64
+	nextRotation   time.Time
+	retryRotation  time.Time
+	now            func() time.Time
+	rename         func(oldpath, newpath string) error
+	signals        chan os.Signal
+	done           chan struct{}
+	backgroundJobs sync.WaitGroup
+	// jobMu serializes compressing and removing backups
+	jobMu sync.Mutex
+}
+
+var _ io.WriteCloser = (*RotatingFile)(nil)
+
+// NewRotatingFile opens (or creates) filename for appending
+func NewRotatingFile(filename string, opts RotateOptions) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		filename: filename,
+		opts:     opts,
+		now:      time.Now,
+		rename:   os.Rename,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	if opts.ReopenOnSIGHUP {
+		rf.signals = make(chan os.Signal, 1)
+		rf.done = make(chan struct{})
+		signal.Notify(rf.signals, syscall.SIGHUP)
+		go rf.handleSignals()
+	}
+	closeOnExit(rf)
+	return rf, nil
+}
+
+func (rf *RotatingFile) handleSignals() {
+	for {
+		select {
+		case <-rf.signals:
+			if err := rf.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "log: unable to reopen %s: %v\n", rf.filename, err)
+			}
+		case <-rf.done:
+			return
+		}
+	}
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	// an existing file written to on an earlier day is rotated on first write
+	start := rf.now()
+	if rf.size > 0 && info.ModTime().Before(start) {
+		start = info.ModTime()
+	}
+	rf.nextRotation = nextMidnight(start)
+	return nil
+}
+
+func nextMidnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.file == nil {
+		// reopening failed during an earlier rotation
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "log: unable to rotate %s: %v\n", rf.filename, err)
+			if rf.file == nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) shouldRotate(writeSize int64) bool {
+	if rf.size == 0 || rf.now().Before(rf.retryRotation) {
+		return false
+	}
+	if rf.opts.MaxSize > 0 && rf.size+writeSize > rf.opts.MaxSize {
+		return true
+	}
+	return rf.opts.Daily && !rf.now().Before(rf.nextRotation)
+}
+
+// Rotate rotates the file immediately
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return err
+		}
+	}
+	return rf.rotate()
+}
+
+// rotate renames the file and opens a new one. If renaming fails the current
+// file is reopened and the next attempt is postponed.
+func (rf *RotatingFile) rotate() error {
+	closeErr := rf.file.Close()
+	rf.file = nil
+	if closeErr != nil {
+		return rf.rotateFailed(closeErr)
+	}
+	backup := rf.backupName()
+	if err := rf.rename(rf.filename, backup); err != nil {
+		return rf.rotateFailed(err)
+	}
+	rf.retryRotation = time.Time{}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.backgroundJobs.Add(1)
+	go func() {
+		defer rf.backgroundJobs.Done()
+		rf.jobMu.Lock()
+		defer rf.jobMu.Unlock()
+		if rf.opts.Compress {
+			if err := compressFile(backup); err != nil {
+				fmt.Fprintf(os.Stderr, "log: unable to compress %s: %v\n", backup, err)
+			}
+		}
+		if err := rf.removeOldBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "log: unable to remove old backups of %s: %v\n", rf.filename, err)
+		}
+	}()
+	return nil
+}
+
+// rotateFailed reopens the current file to keep writing to it and returns err
+func (rf *RotatingFile) rotateFailed(err error) error {
+	rf.retryRotation = rf.now().Add(time.Minute)
+	if openErr := rf.open(); openErr != nil {
+		return fmt.Errorf("%w, reopening failed: %v", err, openErr)
+	}
+	return err
+}
+
+const backupTimeFormat = "20060102-150405.000"
+
+// backupName returns the name of a new backup. Backups rotated within the same
+// millisecond get a zero padded sequence number, e.g. app.log.<timestamp>-001.
+func (rf *RotatingFile) backupName() string {
+	name := rf.filename + "." + rf.now().Format(backupTimeFormat)
+	candidate := name
+	for i := 1; ; i++ {
+		_, errPlain := os.Stat(candidate)
+		_, errGz := os.Stat(candidate + ".gz")
+		if os.IsNotExist(errPlain) && os.IsNotExist(errGz) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%03d", name, i)
+	}
+}
+
+func compressFile(filename string) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(filename+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// removeOldBackups deletes all but the newest MaxBackups rotated files. As the
+// backup names contain a sortable timestamp and sequence number, sorting them
+// by name (without .gz) sorts by age.
+func (rf *RotatingFile) removeOldBackups() error {
+	if rf.opts.MaxBackups <= 0 {
+		return nil
+	}
+	dir, base := filepath.Split(rf.filename)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	backups := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		suffix := strings.TrimPrefix(entry.Name(), base+".")
+		if suffix == entry.Name() || suffix == "" || suffix[0] < '0' || suffix[0] > '9' {
+			continue
+		}
+		// skip files which are yet to be compressed
+		if rf.opts.Compress && !strings.HasSuffix(suffix, ".gz") {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, entry.Name()))
+	}
+	if len(backups) <= rf.opts.MaxBackups {
+		return nil
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+	for _, backup := range backups[:len(backups)-rf.opts.MaxBackups] {
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reopen closes and reopens the file, e.g. after it has been moved by an
+// external tool
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return err
+		}
+		rf.file = nil
+	}
+	return rf.open()
+}
+
+// Sync commits the file's contents to stable storage
+func (rf *RotatingFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Sync()
+}
+
+// Close closes the file and waits for pending compressions to finish
+func (rf *RotatingFile) Close() error {
+	forgetOnExit(rf)
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	rf.closed = true
+	if rf.signals != nil {
+		signal.Stop(rf.signals)
+		close(rf.done)
+	}
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.backgroundJobs.Wait()
+	return err
+}
@@ -0,0 +1,131 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFileBySize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(filename, RotateOptions{MaxSize: 10, MaxBackups: 2, Compress: true})
+	require.Nil(t, err)
+
+	l := New(LevelInfo, WithHandler(NewSink(rf, LevelInfo, &LogfmtFormatter{})))
+	for i := 0; i < 5; i++ {
+		l.Info("line")
+	}
+	require.Nil(t, rf.Close())
+
+	backups, err := filepath.Glob(filename + ".*")
+	require.Nil(t, err)
+	require.Len(t, backups, 2)
+	sort.Strings(backups)
+	for _, backup := range backups {
+		require.Equal(t, ".gz", filepath.Ext(backup))
+		f, err := os.Open(backup)
+		require.Nil(t, err)
+		gz, err := gzip.NewReader(f)
+		require.Nil(t, err)
+		content, err := io.ReadAll(gz)
+		require.Nil(t, err)
+		require.Contains(t, string(content), "msg=line")
+		f.Close()
+	}
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(filename, RotateOptions{Daily: true})
+	require.Nil(t, err)
+	now := time.Date(2021, 5, 1, 23, 59, 0, 0, time.Local)
+	rf.now = func() time.Time { return now }
+	rf.nextRotation = nextMidnight(now)
+
+	// the backup is named after the time of rotation
+	_, err = rf.Write([]byte("day one\n"))
+	require.Nil(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = rf.Write([]byte("day two\n"))
+	require.Nil(t, err)
+	require.Nil(t, rf.Close())
+
+	content, err := os.ReadFile(filename)
+	require.Nil(t, err)
+	require.Equal(t, "day two\n", string(content))
+	content, err = os.ReadFile(filename + ".20210502-000100.000")
+	require.Nil(t, err)
+	require.Equal(t, "day one\n", string(content))
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewRotatingFile(filename, RotateOptions{MaxSize: 10})
+	require.Nil(t, err)
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.Local)
+	rf.now = func() time.Time { return now }
+	renameErr := errors.New("file is busy")
+	rf.rename = func(oldpath, newpath string) error { return renameErr }
+
+	_, err = rf.Write([]byte("first\n"))
+	require.Nil(t, err)
+	require.Equal(t, renameErr, rf.Rotate())
+	// writing continues on the current file instead of failing
+	_, err = rf.Write([]byte("second\n"))
+	require.Nil(t, err)
+	_, err = rf.Write([]byte("third\n"))
+	require.Nil(t, err)
+
+	rf.rename = os.Rename
+	now = now.Add(2 * time.Minute)
+	_, err = rf.Write([]byte("fourth\n"))
+	require.Nil(t, err)
+	require.Nil(t, rf.Close())
+
+	content, err := os.ReadFile(filename)
+	require.Nil(t, err)
+	require.Equal(t, "fourth\n", string(content))
+	content, err = os.ReadFile(filename + ".20210501-120200.000")
+	require.Nil(t, err)
+	require.Equal(t, "first\nsecond\nthird\n", string(content))
+}
+
+func TestRotatingFileSameMillisecond(t *testing.T) {
+	// glob meta characters in the name must not confuse pruning
+	filename := filepath.Join(t.TempDir(), "app[1].log")
+	rf, err := NewRotatingFile(filename, RotateOptions{MaxSize: 2, MaxBackups: 2, Compress: true})
+	require.Nil(t, err)
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.Local)
+	rf.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		_, err = fmt.Fprintf(rf, "%d\n", i)
+		require.Nil(t, err)
+	}
+	require.Nil(t, rf.Close())
+
+	backups, err := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.gz"))
+	require.Nil(t, err)
+	require.Len(t, backups, 2)
+	sort.Strings(backups)
+	prefix := filename + "." + now.Format(backupTimeFormat)
+	require.Equal(t, []string{prefix + "-002.gz", prefix + "-003.gz"}, backups)
+	for i, backup := range backups {
+		f, err := os.Open(backup)
+		require.Nil(t, err)
+		gz, err := gzip.NewReader(f)
+		require.Nil(t, err)
+		content, err := io.ReadAll(gz)
+		require.Nil(t, err)
+		require.Equal(t, fmt.Sprintf("%d\n", i+2), string(content))
+		f.Close()
+	}
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// samplerCleanupThreshold is the number of tracked messages above which
+// expired counters are purged
+const samplerCleanupThreshold = 1024
+
+// Sampler limits how often identical entries (same level and message) are
+// logged: within every interval the first entries are logged, afterwards only
+// every thereafter-th one. Once an interval with suppressed entries ends, a
+// summary entry is logged instead. As the sampler is shared by all loggers
+// derived from the one it was set on, the summary carries no logger name or
+// fields.
+//
+// Entries of LevelPanic and LevelFatal are never sampled.
+type Sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+
+	mu       sync.Mutex
+	counters map[samplerKey]*sampleCounter
+}
+
+type samplerKey struct {
+	level   Level
+	message string
+}
+
+type sampleCounter struct {
+	start      time.Time
+	count      int
+	suppressed int
+	timer      *time.Timer
+}
+
+// NewSampler creates a sampler, a thereafter of 0 suppresses all entries
+// beyond the first ones
+func NewSampler(first, thereafter int, interval time.Duration) *Sampler {
+	return &Sampler{
+		first:      first,
+		thereafter: thereafter,
+		interval:   interval,
+		counters:   make(map[samplerKey]*sampleCounter),
+	}
+}
+
+// allow reports whether e should be logged. l is used to log the summary of
+// suppressed entries.
+func (s *Sampler) allow(l *Logger, e *Entry) bool {
+	if e.Level >= LevelPanic {
+		return true
+	}
+	allowed, summary := s.count(l, e)
+	// dispatched without holding s.mu, as handlers and hooks may log using
+	// the same logger
+	if summary != nil {
+		l.dispatch(summary)
+	}
+	return allowed
+}
+
+// count counts e and reports whether it should be logged, along with the
+// summary of the previous interval if it is due
+func (s *Sampler) count(l *Logger, e *Entry) (bool, *Entry) {
+	key := samplerKey{level: e.Level, message: e.Message}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var summary *Entry
+	counter, exists := s.counters[key]
+	if !exists || e.Time.Sub(counter.start) >= s.interval {
+		if len(s.counters) >= samplerCleanupThreshold {
+			s.cleanup(e.Time)
+		}
+		if exists && counter.timer != nil {
+			// the summary is due but has not been logged yet
+			counter.timer.Stop()
+			summary = s.summarize(l, key, counter)
+		}
+		counter = &sampleCounter{start: e.Time}
+		s.counters[key] = counter
+	}
+
+	counter.count++
+	if counter.count <= s.first {
+		return true, summary
+	}
+	if s.thereafter > 0 && (counter.count-s.first)%s.thereafter == 0 {
+		return true, summary
+	}
+	counter.suppressed++
+	if counter.timer == nil {
+		counter.timer = time.AfterFunc(counter.start.Add(s.interval).Sub(time.Now()), func() {
+			s.mu.Lock()
+			var summary *Entry
+			if s.counters[key] == counter {
+				delete(s.counters, key)
+				summary = s.summarize(l, key, counter)
+			}
+			s.mu.Unlock()
+			if summary != nil {
+				l.dispatch(summary)
+			}
+		})
+	}
+	return false, summary
+}
+
+// cleanup removes counters of expired intervals without suppressed entries.
+// Must be called with s.mu held.
+func (s *Sampler) cleanup(now time.Time) {
+	for key, counter := range s.counters {
+		if counter.timer == nil && now.Sub(counter.start) >= s.interval {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// summarize returns the entry reporting the number of suppressed entries of
+// counter, nil if there are none. Must be called with s.mu held, the entry
+// must be dispatched after releasing it.
+func (s *Sampler) summarize(l *Logger, key samplerKey, counter *sampleCounter) *Entry {
+	if counter.suppressed == 0 {
+		return nil
+	}
+	e := &Entry{
+		Time:    time.Now(),
+		Level:   key.level,
+		Message: "suppressed " + strconv.Itoa(counter.suppressed) + " similar messages",
+	}
+	// the suppressed entries may stem from any logger sharing the sampler, so
+	// the summary carries neither the name nor the fields of l
+	l.derive(emptyContextImpl()).addFields(e, []Field{
+		String("sampled.msg", key.message),
+		Int("sampled.suppressed", counter.suppressed),
+	})
+	return e
+}
+
+// WithSampling samples the entries of the logger and all loggers derived
+// from it, see Sampler
+func WithSampling(first, thereafter int, interval time.Duration) Option {
+	return func(l *Logger) {
+		l.sampler = NewSampler(first, thereafter, interval)
+	}
+}
+
+// Sampled returns a derived logger sampling its entries (and those of loggers
+// derived from it) using its own Sampler
+func (l *Logger) Sampled(first, thereafter int, interval time.Duration) *Logger {
+	newLogger := l.derive(l.fields)
+	newLogger.sampler = NewSampler(first, thereafter, interval)
+	return newLogger
+}
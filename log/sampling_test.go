@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func TestSampling(t *testing.T) {
+	buf := new(syncBuffer)
+	l := New(LevelInfo,
+		WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})),
+		WithSampling(2, 5, 50*time.Millisecond))
+
+	for i := 0; i < 20; i++ {
+		l.WithField("attempt", i).Warn("connection refused")
+	}
+	l.Error("connection refused")
+	// logged: 1, 2, 7, 12, 17 and the error
+	require.Len(t, buf.Lines(), 6)
+
+	require.Eventually(t, func() bool {
+		return len(buf.Lines()) == 7
+	}, time.Second, 5*time.Millisecond)
+	summary := buf.Lines()[6]
+	require.Contains(t, summary, `level=warn`)
+	require.Contains(t, summary, `msg="suppressed 15 similar messages" sampled.msg="connection refused" sampled.suppressed=15`)
+	// the suppressed entries were logged by different loggers
+	require.NotContains(t, summary, "attempt=")
+
+	// a new interval starts afresh
+	l.Warn("connection refused")
+	require.Len(t, buf.Lines(), 8)
+}
+
+func TestSamplingHookLogging(t *testing.T) {
+	buf := new(syncBuffer)
+	l := New(LevelInfo,
+		WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})),
+		WithSampling(1, 0, 20*time.Millisecond))
+
+	// a hook logging through the sampled logger must not deadlock, neither
+	// for regular entries nor for summaries
+	var summaries int32
+	remove := AddHook(func(e *Entry) {
+		if _, ok := e.Fields["sampled.suppressed"]; ok {
+			atomic.AddInt32(&summaries, 1)
+			l.Info("summary seen")
+		}
+	}, HookLevels(LevelWarn))
+	defer remove()
+
+	l.Warn("disk full")
+	l.Warn("disk full")
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&summaries) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// the summary is also due when the next interval starts
+	l.Warn("disk full")
+	l.Warn("disk full")
+	time.Sleep(30 * time.Millisecond)
+	l.Warn("disk full")
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&summaries) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.Contains(t, strings.Join(buf.Lines(), "\n"), "msg=\"summary seen\"")
+}
@@ -0,0 +1,225 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SlogLevel maps level to the corresponding slog level. LevelPanic is mapped
+// to slog.LevelError+4, LevelFatal to slog.LevelError+8.
+func SlogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	case LevelPanic:
+		return slog.LevelError + 4
+	}
+	return slog.LevelError + 8
+}
+
+// LevelFromSlog maps a slog level to the closest level at or below it.
+// Levels above slog.LevelError are mapped to LevelError, slog records never
+// cause a panic or exit.
+func LevelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	}
+	return LevelError
+}
+
+// -------------------------------------------------------------------------- //
+
+// SlogHandler is a slog.Handler passing records to a Logger, so slog calls
+// share the logger's level, fields and handler. Attributes within groups are
+// added as fields named group.key.
+type SlogHandler struct {
+	logger *Logger
+	group  string
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler creates a slog.Handler backed by logger, e.g.
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(logger)))
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	l := LevelFromSlog(level)
+	return h.logger.records(l) || (h.logger.Enabled(l) && h.logger.Handler().Enabled(l))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := &Entry{
+		Time:    r.Time,
+		Level:   LevelFromSlog(r.Level),
+		Name:    h.logger.Name(),
+		Message: r.Message,
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.Caller = &frame
+	}
+	attrs := make(Fields, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(attrs, h.group, a)
+		return true
+	})
+	fields := make([]Field, 0, len(attrs))
+	for _, k := range sortedKeys(attrs) {
+		fields = append(fields, Any(k, attrs[k]))
+	}
+	h.logger.addFields(e, fields)
+	if h.logger.Enabled(e.Level) && h.logger.Handler().Enabled(e.Level) {
+		h.logger.dispatch(e)
+	} else {
+		h.logger.record(e)
+	}
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(Fields, len(attrs))
+	for _, a := range attrs {
+		addSlogAttr(fields, h.group, a)
+	}
+	return &SlogHandler{logger: h.logger.WithFields(fields), group: h.group}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, group: prefixKey(h.group, name)}
+}
+
+func prefixKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// addSlogAttr adds a to fields, flattening groups into dotted keys
+func addSlogAttr(fields Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefixKey(prefix, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, groupPrefix, ga)
+		}
+		return
+	}
+	fields[prefixKey(prefix, a.Key)] = a.Value.Any()
+}
+
+// -------------------------------------------------------------------------- //
+
+// SlogSink is a Handler writing entries into a slog.Handler. The entry's
+// level is mapped using SlogLevel and its fields become attributes of the
+// record. The fields of a logger (i.e. those added by WithField and
+// WithFields) are passed to the slog.Handler using WithAttrs once per logger,
+// only the fields of the individual entries are added to the records, unless
+// they replace a field of the logger.
+type SlogSink struct {
+	handler slog.Handler
+}
+
+var _ Handler = (*SlogSink)(nil)
+
+// NewSlogSink creates a Handler writing into h, e.g.
+//
+//	logger := log.New(log.LevelInfo, log.WithHandler(log.NewSlogSink(slog.Default().Handler())))
+func NewSlogSink(h slog.Handler) *SlogSink {
+	return &SlogSink{handler: h}
+}
+
+func (s *SlogSink) Enabled(level Level) bool {
+	return s.handler.Enabled(context.Background(), SlogLevel(level))
+}
+
+func (s *SlogSink) Handle(e *Entry) error {
+	var pc uintptr
+	if e.Caller != nil {
+		pc = e.Caller.PC
+	}
+	r := slog.NewRecord(e.Time, SlogLevel(e.Level), e.Message, pc)
+	handler := s.handler
+	if ci, ok := e.context.(*contextImpl); ok && !ci.shadowedBy(e.fields) {
+		handler = ci.slogHandler(s, e.redaction)
+		for _, f := range e.fields {
+			// the value in Fields is redacted already
+			r.AddAttrs(slog.Any(f.Key, e.Fields[f.Key]))
+		}
+	} else {
+		for _, k := range sortedKeys(e.Fields) {
+			r.AddAttrs(slog.Any(k, e.Fields[k]))
+		}
+	}
+	return handler.Handle(context.Background(), r)
+}
+
+// shadowedBy reports whether any of fields has the key of a field of ci. Such
+// entries are not passed to the cached handler, as the key would appear twice.
+func (ci *contextImpl) shadowedBy(fields []Field) bool {
+	flat := ci.flatten()
+	for _, f := range fields {
+		if _, ok := flat[f.Key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// slogHandlerKey identifies the handlers cached by contextImpl.slogHandler
+type slogHandlerKey struct {
+	sink      *SlogSink
+	redaction *RedactionPolicy
+}
+
+// slogHandler returns the handler of sink with the (redacted) fields of ci
+// added as attributes, creating it on first use
+func (ci *contextImpl) slogHandler(sink *SlogSink, redaction *RedactionPolicy) slog.Handler {
+	cache := ci.cached()
+	key := slogHandlerKey{sink: sink, redaction: redaction}
+	if h, ok := cache.slogHandlers.Load(key); ok {
+		return h.(slog.Handler)
+	}
+	if len(cache.flat) == 0 {
+		return sink.handler
+	}
+	fields := ci.Map()
+	if redaction != nil {
+		fields = redaction.Apply(fields)
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, k := range sortedKeys(fields) {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	h, _ := cache.slogHandlers.LoadOrStore(key, sink.handler.WithAttrs(attrs))
+	return h.(slog.Handler)
+}
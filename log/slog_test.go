@@ -0,0 +1,84 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+	logger := slog.New(NewSlogHandler(l.WithField("service", "api")))
+
+	logger.Debug("filtered")
+	require.Equal(t, "", buf.String())
+
+	logger.With("user", "jane").WithGroup("req").Warn("slow", "ms", 1200, slog.Group("db", "queries", 3))
+	require.Contains(t, buf.String(), "level=warn caller=slog_test.go:")
+	require.Contains(t, buf.String(), `msg=slow req.db.queries=3 req.ms=1200 service=api user=jane`)
+
+	// the name of a named logger is kept
+	buf.Reset()
+	slog.New(NewSlogHandler(l.Named("db"))).Warn("named")
+	require.Contains(t, buf.String(), "level=warn logger=db caller=")
+}
+
+func TestSlogSink(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	l := New(LevelDebug, WithHandler(NewSlogSink(h)))
+
+	l.Info("filtered")
+	require.Equal(t, "", buf.String())
+
+	l.WithFields(Fields{"user": "jane"}).WithField("attempt", 2).Error("failed")
+	record := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "ERROR", record["level"])
+	require.Equal(t, "failed", record["msg"])
+	require.Equal(t, "jane", record["user"])
+	require.Equal(t, 2.0, record["attempt"])
+}
+
+// countingSlogHandler counts the calls to WithAttrs
+type countingSlogHandler struct {
+	slog.Handler
+	withAttrs *int32
+}
+
+func (h countingSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	atomic.AddInt32(h.withAttrs, 1)
+	return countingSlogHandler{Handler: h.Handler.WithAttrs(attrs), withAttrs: h.withAttrs}
+}
+
+func TestSlogSinkWithAttrs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var withAttrs int32
+	h := countingSlogHandler{Handler: slog.NewTextHandler(buf, nil), withAttrs: &withAttrs}
+	l := New(LevelDebug, WithHandler(NewSlogSink(h)))
+
+	requestLogger := l.WithFields(Fields{"request": 1, "password": "hunter2"})
+	for i := 0; i < 3; i++ {
+		requestLogger.InfoFields("handled", Int("status", 200))
+	}
+	l.Info("plain")
+	require.Equal(t, int32(1), atomic.LoadInt32(&withAttrs))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Contains(t, lines[0], `msg=handled password=<redacted> request=1 status=200`)
+	require.NotContains(t, lines[3], "request")
+
+	// a field replacing one of the logger is sent once, with its own value
+	buf.Reset()
+	requestLogger.InfoFields("retried", Int("request", 2))
+	require.Contains(t, buf.String(), `msg=retried`)
+	require.Equal(t, 1, strings.Count(buf.String(), "request="))
+	require.Contains(t, buf.String(), "request=2")
+}
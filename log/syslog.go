@@ -0,0 +1,390 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogFacility is the facility of a syslog message (RFC 5424, 6.2.1)
+type SyslogFacility int
+
+const (
+	FacilityKern SyslogFacility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLocal0 SyslogFacility = iota + 10
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// SyslogOptions configures a SyslogSink
+type SyslogOptions struct {
+	// Facility defaults to FacilityUser. As FacilityKern is 0 it is treated
+	// as unset, the kernel facility can not be used by the sink.
+	Facility SyslogFacility
+	// AppName defaults to the name of the executable
+	AppName string
+	// Hostname defaults to os.Hostname
+	Hostname string
+	// StructuredDataID is the SD-ID of the element carrying the fields,
+	// defaults to "fields@32473"
+	StructuredDataID string
+	// DialTimeout limits connecting and writing a message, defaults to 5
+	// seconds
+	DialTimeout time.Duration
+	// MinBackoff is the time to wait before reconnecting after a failure, it
+	// doubles with every further failure up to MaxBackoff. Defaults to 100ms
+	// and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxDatagramSize is the size longer messages are truncated to for udp
+	// and unixgram sockets, defaults to 2048 bytes (see RFC 5426, 3.2)
+	MaxDatagramSize int
+}
+
+// syslogErrorInterval limits how often Handle reports dropped entries
+const syslogErrorInterval = time.Minute
+
+// SyslogSink is a Handler sending entries as RFC 5424 messages to a syslog
+// server. The level is mapped to the severity (see SyslogSeverity), the name
+// of a named logger becomes the MSGID and the fields are sent as structured
+// data.
+//
+// For tcp and unix stream sockets messages are framed using octet counting
+// (RFC 6587), for udp and unixgram sockets every message is a datagram. If
+// sending fails the connection is reestablished in the background, with an
+// exponential backoff. Entries are dropped until then, Handle reports this
+// once a minute at most.
+type SyslogSink struct {
+	network string
+	address string
+	opts    SyslogOptions
+	procID  string
+	done    chan struct{}
+
+	mu           sync.Mutex
+	level        Level
+	conn         net.Conn
+	stream       bool
+	reconnecting bool
+	lastError    error
+	dropped      int
+	lastReport   time.Time
+	closed       bool
+}
+
+var _ Handler = (*SyslogSink)(nil)
+var _ io.Closer = (*SyslogSink)(nil)
+
+// NewSyslogSink creates a sink sending entries of at least level to the
+// syslog server at address. network is one of tcp, tcp4, tcp6, udp, udp4,
+// udp6, unix (stream or datagram, whichever the socket supports) or unixgram.
+//
+// The connection is established right away, if this fails the sink keeps
+// retrying in the background.
+func NewSyslogSink(network, address string, level Level, opts SyslogOptions) (*SyslogSink, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+	default:
+		return nil, errors.New("syslog: unsupported network '" + network + "'")
+	}
+	if opts.Facility == 0 {
+		opts.Facility = FacilityUser
+	}
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.StructuredDataID == "" {
+		opts.StructuredDataID = "fields@32473"
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.MaxDatagramSize <= 0 {
+		opts.MaxDatagramSize = 2048
+	}
+
+	s := &SyslogSink{
+		network: network,
+		address: address,
+		opts:    opts,
+		procID:  strconv.Itoa(os.Getpid()),
+		done:    make(chan struct{}),
+		level:   level,
+	}
+	conn, stream, err := s.dial()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.disconnect(err)
+	} else {
+		s.conn, s.stream = conn, stream
+	}
+	return s, nil
+}
+
+// SyslogSeverity maps level to a syslog severity: debug (7), informational
+// (6), warning (4), error (3), critical (2) for LevelPanic and alert (1) for
+// LevelFatal. Unknown levels are mapped to notice (5).
+func SyslogSeverity(level Level) int {
+	switch level {
+	case LevelDebug:
+		return 7
+	case LevelInfo:
+		return 6
+	case LevelWarn:
+		return 4
+	case LevelError:
+		return 3
+	case LevelPanic:
+		return 2
+	case LevelFatal:
+		return 1
+	}
+	return 5
+}
+
+func (s *SyslogSink) Enabled(level Level) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return level >= s.level
+}
+
+// SetLevel changes the minimum level of entries sent by the sink
+func (s *SyslogSink) SetLevel(level Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.level = level
+}
+
+// usesCaller returns false, the caller is not part of syslog messages
+func (s *SyslogSink) usesCaller() bool {
+	return false
+}
+
+func (s *SyslogSink) Handle(e *Entry) error {
+	msg := s.format(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("syslog: sink is closed")
+	}
+	if s.conn == nil {
+		return s.drop()
+	}
+	if err := s.write(msg); err != nil {
+		s.disconnect(err)
+		return s.drop()
+	}
+	return nil
+}
+
+// dial connects to the server, returning whether the connection is a stream
+func (s *SyslogSink) dial() (net.Conn, bool, error) {
+	if s.network == "unix" {
+		// syslog daemons mostly listen on datagram sockets
+		if conn, err := net.DialTimeout("unixgram", s.address, s.opts.DialTimeout); err == nil {
+			return conn, false, nil
+		}
+		conn, err := net.DialTimeout("unix", s.address, s.opts.DialTimeout)
+		return conn, true, err
+	}
+	conn, err := net.DialTimeout(s.network, s.address, s.opts.DialTimeout)
+	stream := s.network != "udp" && s.network != "udp4" && s.network != "udp6" && s.network != "unixgram"
+	return conn, stream, err
+}
+
+// disconnect drops the connection and starts reconnecting in the background.
+// Must be called with s.mu held.
+func (s *SyslogSink) disconnect(err error) {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.lastError = err
+	if !s.reconnecting {
+		s.reconnecting = true
+		go s.reconnect()
+	}
+}
+
+// reconnect dials the server until it succeeds or the sink is closed
+func (s *SyslogSink) reconnect() {
+	backoff := s.opts.MinBackoff
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-s.done:
+			return
+		}
+		conn, stream, err := s.dial()
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		if err != nil {
+			s.lastError = err
+			s.mu.Unlock()
+			if backoff *= 2; backoff > s.opts.MaxBackoff {
+				backoff = s.opts.MaxBackoff
+			}
+			continue
+		}
+		s.conn, s.stream = conn, stream
+		s.reconnecting = false
+		dropped := s.dropped
+		s.dropped = 0
+		s.lastReport = time.Time{}
+		s.mu.Unlock()
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "log: syslog: reconnected to %s, %d entries were dropped\n", s.address, dropped)
+		}
+		return
+	}
+}
+
+// drop counts an entry dropped while disconnected and returns an error unless
+// one has been returned within the last syslogErrorInterval.
+// Must be called with s.mu held.
+func (s *SyslogSink) drop() error {
+	s.dropped++
+	if now := time.Now(); now.Sub(s.lastReport) >= syslogErrorInterval {
+		s.lastReport = now
+		return fmt.Errorf("syslog: %w, dropping entries until reconnected", s.lastError)
+	}
+	return nil
+}
+
+// write sends msg using the framing of the connection.
+// Must be called with s.mu held.
+func (s *SyslogSink) write(msg []byte) error {
+	if s.stream {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	} else if len(msg) > s.opts.MaxDatagramSize {
+		n := s.opts.MaxDatagramSize
+		// do not cut a multi-byte UTF-8 character in half
+		for n > 0 && msg[n]&0xC0 == 0x80 {
+			n--
+		}
+		msg = msg[:n]
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.DialTimeout))
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+// format renders e as RFC 5424 message:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID key="value"...] MSG
+func (s *SyslogSink) format(e *Entry) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("<" + strconv.Itoa(int(s.opts.Facility)*8+SyslogSeverity(e.Level)) + ">1 ")
+	buf.WriteString(e.Time.Format("2006-01-02T15:04:05.000000Z07:00") + " ")
+	buf.WriteString(syslogHeaderField(s.opts.Hostname, 255) + " ")
+	buf.WriteString(syslogHeaderField(s.opts.AppName, 48) + " ")
+	buf.WriteString(syslogHeaderField(s.procID, 128) + " ")
+	buf.WriteString(syslogHeaderField(e.Name, 32) + " ")
+	if len(e.Fields) == 0 {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteString("[" + s.opts.StructuredDataID)
+		for _, k := range sortedKeys(e.Fields) {
+			buf.WriteString(" " + syslogParamName(k) + `="`)
+			syslogEscape(buf, fmt.Sprint(e.Fields[k]))
+			buf.WriteByte('"')
+		}
+		buf.WriteByte(']')
+	}
+	if e.Message != "" {
+		buf.WriteString(" " + e.Message)
+	}
+	return buf.Bytes()
+}
+
+// syslogHeaderField restricts s to printable ASCII without spaces and the
+// given length, empty values are rendered as the NILVALUE "-"
+func syslogHeaderField(s string, maxLen int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	return string(b)
+}
+
+// syslogParamName restricts a field key to the characters allowed in an
+// SD-NAME: printable ASCII except '=', ' ', ']' and '"', at most 32 bytes
+func syslogParamName(key string) string {
+	b := []byte(syslogHeaderField(key, 32))
+	for i, c := range b {
+		if c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// syslogEscape writes a PARAM-VALUE, escaping '"', '\' and ']'
+func syslogEscape(buf *bytes.Buffer, value string) {
+	for _, r := range value {
+		if r == '"' || r == '\\' || r == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// Close closes the connection, further entries are rejected
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+var syslogOpts = SyslogOptions{
+	Facility:   FacilityLocal0,
+	AppName:    "app",
+	Hostname:   "host",
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s, err := NewSyslogSink("udp", "127.0.0.1:1", LevelInfo, syslogOpts)
+	require.Nil(t, err)
+	defer s.Close()
+
+	e := testEntry()
+	e.Name = "db.pool"
+	e.Fields = Fields{"path": `C:\tmp [x]`, "user name": "jane \"doe\""}
+	msg := string(s.format(e))
+	require.Regexp(t, regexp.MustCompile(`^<132>1 2021-05-01T12:30:00.000000Z host app \d+ db.pool `+
+		regexp.QuoteMeta(`[fields@32473 path="C:\\tmp [x\]" user_name="jane \"doe\""] a message`)+`$`), msg)
+
+	e.Name, e.Fields, e.Level = "", nil, LevelFatal
+	require.Regexp(t, `^<129>1 \S+ host app \d+ - - a message$`, string(s.format(e)))
+
+	_, err = NewSyslogSink("carrier-pigeon", "", LevelInfo, syslogOpts)
+	require.NotNil(t, err)
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer pc.Close()
+
+	s, err := NewSyslogSink("udp", pc.LocalAddr().String(), LevelInfo, syslogOpts)
+	require.Nil(t, err)
+	defer s.Close()
+	l := New(LevelDebug, WithHandler(s))
+	l.Debug("filtered")
+	l.WithField("n", 1).Warn("disk full")
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.Nil(t, err)
+	require.Regexp(t, `^<132>1 \S+ host app \d+ - \[fields@32473 n="1"\] disk full$`, string(buf[:n]))
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	require.Nil(t, err)
+	defer pc.Close()
+
+	s, err := NewSyslogSink("unix", path, LevelInfo, syslogOpts)
+	require.Nil(t, err)
+	defer s.Close()
+	require.Nil(t, s.Handle(testEntry()))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(string(buf[:n]), "] a message"))
+}
+
+// readOctetCounted reads a single message framed by octet counting
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	s, err := NewSyslogSink("tcp", ln.Addr().String(), LevelInfo, syslogOpts)
+	require.Nil(t, err)
+	defer s.Close()
+
+	e := testEntry()
+	e.Fields = nil
+	e.Message = "first\nline"
+	require.Nil(t, s.Handle(e))
+	conn := <-conns
+	msg, err := readOctetCounted(bufio.NewReader(conn))
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(msg, " - first\nline"))
+
+	// the server drops the connection, the sink reconnects
+	conn.Close()
+	e.Message = "second"
+	var reconnected net.Conn
+	require.Eventually(t, func() bool {
+		s.Handle(e)
+		select {
+		case reconnected = <-conns:
+			return true
+		default:
+			return false
+		}
+	}, 2*time.Second, 5*time.Millisecond)
+	defer reconnected.Close()
+	msg, err = readOctetCounted(bufio.NewReader(reconnected))
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(msg, " - second"))
+}
+
+func TestSyslogOutage(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	address := ln.Addr().String()
+	ln.Close()
+
+	// the server is down: entries are dropped without blocking and reported
+	// once only
+	s, err := NewSyslogSink("tcp", address, LevelInfo, syslogOpts)
+	require.Nil(t, err)
+	defer s.Close()
+	e := testEntry()
+	e.Fields = nil
+	require.NotNil(t, s.Handle(e))
+	require.Nil(t, s.Handle(e))
+
+	ln, err = net.Listen("tcp", address)
+	require.Nil(t, err)
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	var conn net.Conn
+	select {
+	case conn = <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the sink did not reconnect")
+	}
+	defer conn.Close()
+	require.Eventually(t, func() bool {
+		return s.Handle(e) == nil && func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.conn != nil
+		}()
+	}, time.Second, 5*time.Millisecond)
+	msg, err := readOctetCounted(bufio.NewReader(conn))
+	require.Nil(t, err)
+	require.True(t, strings.HasSuffix(msg, " - a message"))
+}
+
+func TestSyslogDatagramSize(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer pc.Close()
+
+	opts := syslogOpts
+	opts.MaxDatagramSize = 100
+	s, err := NewSyslogSink("udp", pc.LocalAddr().String(), LevelInfo, opts)
+	require.Nil(t, err)
+	defer s.Close()
+	e := testEntry()
+	e.Message = strings.Repeat("ä", 100)
+	require.Nil(t, s.Handle(e))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.Nil(t, err)
+	require.True(t, n <= 100 && n >= 99)
+	require.True(t, utf8.Valid(buf[:n]))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	require.Equal(t, 7, SyslogSeverity(LevelDebug))
+	require.Equal(t, 1, SyslogSeverity(LevelFatal))
+	require.Equal(t, 5, SyslogSeverity(Level(42)))
+	require.Equal(t, 5, SyslogSeverity(Level(-1)))
+}
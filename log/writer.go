@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	stdlog "log"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxWriterLineSize is the length beyond which a Writer logs an incomplete
+// line rather than buffering it any further, like bufio.MaxScanTokenSize
+const maxWriterLineSize = 64 * 1024
+
+// writerSkipPackages are the packages skipped when determining the caller of
+// a Writer, as they merely pass data through
+var writerSkipPackages = map[string]bool{
+	"log":   true,
+	"fmt":   true,
+	"io":    true,
+	"bufio": true,
+}
+
+// levelWriter implements Logger.Writer
+type levelWriter struct {
+	logger *Logger
+	level  Level
+
+	maxLine int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Writer returns an io.Writer logging every line written at the given level,
+// with the fields of the logger. A trailing incomplete line is kept until it
+// is completed by a later write or the writer is closed (it implements
+// io.Closer). Once it exceeds 64 KiB, its first 64 KiB are logged as a line
+// of their own. The caller is the function writing into the writer, skipping
+// the packages log, fmt, io and bufio of the standard library.
+func (l *Logger) Writer(level Level) io.Writer {
+	return &levelWriter{logger: l, level: level, maxLine: maxWriterLineSize}
+}
+
+// StdLogger returns a logger of the standard library writing into
+// l.Writer(level), e.g. to be used as http.Server.ErrorLog
+func (l *Logger) StdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(l.Writer(level), "", 0)
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if !w.logger.accepts(w.level) {
+		return len(p), nil
+	}
+	w.mu.Lock()
+	w.buf = append(w.buf, p...)
+	lines := make([]string, 0, 1)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) > w.maxLine {
+		n := w.maxLine
+		// do not cut a multi-byte UTF-8 character in half
+		for n > 0 && !utf8.RuneStart(w.buf[n]) {
+			n--
+		}
+		if n == 0 {
+			n = w.maxLine
+		}
+		lines = append(lines, string(w.buf[:n]))
+		w.buf = w.buf[n:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	w.mu.Unlock()
+
+	if len(lines) > 0 {
+		caller := w.findCaller()
+		for _, line := range lines {
+			w.logLine(line, caller)
+		}
+	}
+	return len(p), nil
+}
+
+// Close logs a remaining incomplete line
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	line := string(w.buf)
+	w.buf = nil
+	w.mu.Unlock()
+	if line != "" {
+		w.logLine(line, w.findCaller())
+	}
+	return nil
+}
+
+// findCaller returns the function writing into the writer, if it is used
+func (w *levelWriter) findCaller() *runtime.Frame {
+	if !w.logger.needsCaller(w.level) {
+		return nil
+	}
+	return findCaller(writerSkipPackages)
+}
+
+func (w *levelWriter) logLine(line string, caller *runtime.Frame) {
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" {
+		return
+	}
+	w.logger.emit(&Entry{
+		Time:    time.Now(),
+		Level:   w.level,
+		Name:    w.logger.Name(),
+		Caller:  caller,
+		Message: line,
+	}, nil)
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{}))).WithField("lib", "driver")
+
+	w := l.Writer(LevelWarn)
+	_, _, line, _ := runtime.Caller(0)
+	fmt.Fprintf(w, "first line\nsecond ")
+	fmt.Fprintf(w, "line\r\n\npartial")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], fmt.Sprintf("level=warn caller=writer_test.go:%d msg=\"first line\" lib=driver", line+1))
+	require.Contains(t, lines[1], fmt.Sprintf("caller=writer_test.go:%d msg=\"second line\" lib=driver", line+2))
+
+	require.Nil(t, w.(io.Closer).Close())
+	require.Contains(t, buf.String(), "msg=partial")
+
+	// disabled levels are discarded
+	buf.Reset()
+	fmt.Fprintln(l.Writer(LevelDebug), "filtered")
+	require.Equal(t, "", buf.String())
+}
+
+func TestStdLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	std := l.Named("http").StdLogger(LevelError)
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("http: TLS handshake error from %s", "10.0.0.1")
+	std.Println("multi\nline")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], fmt.Sprintf(`level=error logger=http caller=writer_test.go:%d msg="http: TLS handshake error from 10.0.0.1"`, line+1))
+	require.Contains(t, lines[1], fmt.Sprintf("caller=writer_test.go:%d msg=multi", line+2))
+	require.Contains(t, lines[2], "msg=line")
+}
+
+func TestWriterLongLine(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := New(LevelInfo, WithHandler(NewSink(buf, LevelDebug, &LogfmtFormatter{})))
+
+	w := l.Writer(LevelInfo)
+	w.(*levelWriter).maxLine = 8
+	fmt.Fprint(w, "01234")
+	require.Equal(t, "", buf.String())
+	fmt.Fprint(w, "56ä89")
+	fmt.Fprint(w, "x\n")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasSuffix(lines[0], "msg=0123456"))
+	require.Contains(t, lines[1], "msg=ä89x")
+}